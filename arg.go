@@ -0,0 +1,80 @@
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package debounce
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// NewWithArg is like New, but the debounced function also takes an argument
+// which is passed on to f. The last argument wins, just like the last function.
+func NewWithArg[T any](after time.Duration, countLimit uint64, opts ...Option) func(arg T, f func(T)) {
+	a := &argDebouncer[T]{
+		d: NewDebouncer(after, countLimit, unclosable(opts)...),
+	}
+	// Pass the same function to every Do instead of allocating a closure
+	// for every call, see BenchmarkDebounceArgClosure.
+	a.run = a.fire
+
+	return a.Do
+}
+
+// NewWithCoalesce is like NewWithArg without a count limit, but a call only
+// replaces the pending argument if coalesce reports that it can be merged
+// with it. Otherwise, the pending argument is passed on to its f right away
+// and a new quiet period starts for the new one, e.g. so that edits to
+// another document flush the previous one rather than replace it.
+func NewWithCoalesce[T any](after time.Duration, coalesce func(prev, next T) bool, opts ...Option) func(arg T, f func(T)) {
+	a := &argDebouncer[T]{
+		d:        NewDebouncer(after, math.MaxUint64, unclosable(opts)...),
+		coalesce: coalesce,
+	}
+	a.run = a.fire
+
+	return a.Do
+}
+
+type argDebouncer[T any] struct {
+	mu       sync.Mutex
+	d        *CountDebouncer
+	coalesce func(prev, next T) bool
+	arg      T
+	f        func(T)
+	run      func()
+}
+
+func (a *argDebouncer[T]) Do(arg T, f func(T)) {
+	if a.coalesce != nil {
+		a.mu.Lock()
+		flush := a.f != nil && !a.coalesce(a.arg, arg)
+		a.mu.Unlock()
+
+		if flush {
+			a.d.Flush()
+		}
+	}
+
+	a.mu.Lock()
+	a.arg, a.f = arg, f
+	a.mu.Unlock()
+
+	a.d.Do(a.run)
+}
+
+func (a *argDebouncer[T]) fire() {
+	var zero T
+
+	a.mu.Lock()
+	arg, f := a.arg, a.f
+	a.arg, a.f = zero, nil
+	a.mu.Unlock()
+
+	// f is nil if the latest argument was already passed on by an earlier
+	// execution, e.g. when the count limit was reached.
+	if f != nil {
+		f(arg)
+	}
+}
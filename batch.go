@@ -0,0 +1,69 @@
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package debounce
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// NewBatch returns a debounced function which collects all items it is called
+// with and passes them to handler once it stops being called for the given
+// duration, or as soon as maxItems items have been collected. A maxItems of
+// zero means no limit.
+//
+// handler owns the slice it is passed; subsequent items are collected into a
+// new slice. Calling flush passes the collected items, if any, to handler
+// right away.
+func NewBatch[T any](after time.Duration, maxItems int, handler func(items []T), opts ...Option) (add func(item T), flush func()) {
+	b := &batch[T]{
+		d:        NewDebouncer(after, math.MaxUint64, unclosable(opts)...),
+		maxItems: maxItems,
+		handler:  handler,
+	}
+
+	return b.add, b.d.Flush
+}
+
+type batch[T any] struct {
+	mu       sync.Mutex
+	d        *CountDebouncer
+	maxItems int
+	items    []T
+	full     [][]T // Full batches waiting to be handled.
+	handler  func([]T)
+}
+
+func (b *batch[T]) add(item T) {
+	b.mu.Lock()
+	b.items = append(b.items, item)
+	full := b.maxItems > 0 && len(b.items) >= b.maxItems
+	if full {
+		// Set the full batch aside, so that concurrent calls can't add to
+		// it before it is handled.
+		b.full = append(b.full, b.items)
+		b.items = nil
+	}
+	b.mu.Unlock()
+
+	b.d.Do(b.flush)
+	if full {
+		b.d.Flush()
+	}
+}
+
+func (b *batch[T]) flush() {
+	b.mu.Lock()
+	batches := b.full
+	if len(b.items) > 0 {
+		batches = append(batches, b.items)
+	}
+	b.full, b.items = nil, nil
+	b.mu.Unlock()
+
+	for _, items := range batches {
+		b.handler(items)
+	}
+}
@@ -0,0 +1,92 @@
+package debounce_test
+
+import (
+	"reflect"
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/qpoint-io/debounce"
+)
+
+func TestBatch(t *testing.T) {
+	var (
+		mu      sync.Mutex
+		batches [][]int
+	)
+
+	handler := func(items []int) {
+		mu.Lock()
+		defer mu.Unlock()
+		batches = append(batches, items)
+	}
+
+	add, flush := debounce.NewBatch(50*time.Millisecond, 3, handler)
+
+	// Reaching the max items flushes right away.
+	for i := 1; i <= 5; i++ {
+		add(i)
+	}
+
+	mu.Lock()
+	if want := [][]int{{1, 2, 3}}; !reflect.DeepEqual(batches, want) {
+		t.Errorf("Expected batches %v, was %v", want, batches)
+	}
+	mu.Unlock()
+
+	// The rest is flushed once the window goes quiet.
+	time.Sleep(100 * time.Millisecond)
+
+	add(6)
+	flush()
+	flush()
+
+	mu.Lock()
+	if want := [][]int{{1, 2, 3}, {4, 5}, {6}}; !reflect.DeepEqual(batches, want) {
+		t.Errorf("Expected batches %v, was %v", want, batches)
+	}
+	mu.Unlock()
+}
+
+func TestBatchConcurrentMaxItems(t *testing.T) {
+	var (
+		mu    sync.Mutex
+		total int
+	)
+
+	handler := func(items []int) {
+		mu.Lock()
+		defer mu.Unlock()
+		if len(items) > 10 {
+			t.Errorf("Expected at most 10 items, was %d", len(items))
+		}
+		total += len(items)
+	}
+
+	// Yield before every execution, letting other calls add items in
+	// between.
+	add, flush := debounce.NewBatch(time.Hour, 10, handler, debounce.WithDispatch(func(task func()) {
+		runtime.Gosched()
+		task()
+	}))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				add(j)
+			}
+		}()
+	}
+	wg.Wait()
+	flush()
+
+	mu.Lock()
+	defer mu.Unlock()
+	if total != 5000 {
+		t.Errorf("Expected 5000 items, was %d", total)
+	}
+}
@@ -0,0 +1,119 @@
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package debounce
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// NewChan returns a debounced trigger with the same semantics as New, which
+// sends a value on out every time the debounced window fires, instead of
+// executing a function.
+//
+// out has a buffer of one. If a value is already waiting to be received when
+// the window fires, the new value is dropped, so a slow receiver sees at most
+// one pending tick.
+//
+// close stops the debouncer, dropping any pending tick, and closes out. It may
+// be called more than once.
+func NewChan(after time.Duration, countLimit uint64, opts ...Option) (trigger func(), out <-chan struct{}, close func()) {
+	c := &debounceChan{
+		d:   NewDebouncer(after, countLimit, opts...),
+		out: make(chan struct{}, 1),
+	}
+
+	return c.trigger, c.out, c.close
+}
+
+type debounceChan struct {
+	mu     sync.Mutex
+	d      *CountDebouncer
+	out    chan struct{}
+	closed bool
+}
+
+func (c *debounceChan) trigger() {
+	c.d.Do(c.send)
+}
+
+func (c *debounceChan) send() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.closed {
+		return
+	}
+
+	select {
+	case c.out <- struct{}{}:
+	default:
+	}
+}
+
+func (c *debounceChan) close() {
+	c.d.Close()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.closed {
+		c.closed = true
+		close(c.out)
+	}
+}
+
+// DebounceChannel returns a channel on which the latest value received from
+// in is sent once in has been quiet for the given duration. When in is
+// closed, the pending value, if any, is sent right away and the returned
+// channel is closed.
+func DebounceChannel[T any](in <-chan T, after time.Duration, opts ...Option) <-chan T {
+	c := &debouncedChannel[T]{
+		d:   NewDebouncer(after, math.MaxUint64, opts...),
+		out: make(chan T),
+	}
+	c.run = c.send
+
+	go c.loop(in)
+
+	return c.out
+}
+
+type debouncedChannel[T any] struct {
+	mu      sync.Mutex
+	d       *CountDebouncer
+	out     chan T
+	latest  T
+	pending bool
+	run     func()
+}
+
+func (c *debouncedChannel[T]) loop(in <-chan T) {
+	for v := range in {
+		c.mu.Lock()
+		c.latest, c.pending = v, true
+		c.mu.Unlock()
+
+		c.d.Do(c.run)
+	}
+
+	c.d.Flush()
+	c.d.Wait()
+	c.d.Close()
+	close(c.out)
+}
+
+func (c *debouncedChannel[T]) send() {
+	var zero T
+
+	c.mu.Lock()
+	v, pending := c.latest, c.pending
+	c.latest, c.pending = zero, false
+	c.mu.Unlock()
+
+	if pending {
+		c.out <- v
+	}
+}
@@ -0,0 +1,68 @@
+package debounce_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/qpoint-io/debounce"
+)
+
+func TestChan(t *testing.T) {
+	trigger, out, closeChan := debounce.NewChan(20*time.Millisecond, 1000)
+
+	for i := 0; i < 10; i++ {
+		trigger()
+	}
+
+	select {
+	case <-out:
+	case <-time.After(time.Second):
+		t.Fatal("Expected a tick")
+	}
+
+	// Ticks are dropped while one is waiting to be received.
+	for i := 0; i < 3; i++ {
+		trigger()
+		time.Sleep(50 * time.Millisecond)
+	}
+
+	ticks := 0
+	closeChan()
+	closeChan()
+	for range out {
+		ticks++
+	}
+
+	if ticks != 1 {
+		t.Error("Expected 1 buffered tick, was", ticks)
+	}
+
+	// Triggering after close is a no-op.
+	trigger()
+}
+
+func TestDebounceChannel(t *testing.T) {
+	in := make(chan int)
+	out := debounce.DebounceChannel(in, 20*time.Millisecond)
+
+	go func() {
+		for i := 0; i < 10; i++ {
+			in <- i
+		}
+		time.Sleep(50 * time.Millisecond)
+
+		in <- 10
+		in <- 11
+		// Closing in flushes the pending value right away.
+		close(in)
+	}()
+
+	var got []int
+	for v := range out {
+		got = append(got, v)
+	}
+
+	if len(got) != 2 || got[0] != 9 || got[1] != 11 {
+		t.Errorf("expected [9 11], got %v", got)
+	}
+}
@@ -0,0 +1,113 @@
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package debounce
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// A Clock tells the time and schedules the debouncer's timers.
+// See WithTimerClock.
+type Clock interface {
+	Now() time.Time
+	// AfterFunc calls f in its own goroutine, or synchronously for a
+	// ManualClock, once the duration has elapsed.
+	AfterFunc(d time.Duration, f func()) Timer
+}
+
+// A Timer is a timer scheduled by a Clock. *time.Timer implements Timer.
+type Timer interface {
+	// Stop prevents the timer from firing and reports whether it was still
+	// active.
+	Stop() bool
+}
+
+// afterFunc is the default implementation of Clock.AfterFunc.
+func afterFunc(d time.Duration, f func()) Timer {
+	return time.AfterFunc(d, f)
+}
+
+// ManualClock is a Clock which only moves forward when advanced, firing the
+// timers whose time has come synchronously. It makes tests fast and
+// deterministic, as they don't have to sleep for real timers to fire.
+type ManualClock struct {
+	mu     sync.Mutex
+	now    time.Time
+	timers []*manualTimer
+}
+
+// NewManualClock returns a ManualClock set to the given time.
+func NewManualClock(now time.Time) *ManualClock {
+	return &ManualClock{now: now}
+}
+
+// Now returns the current time of the clock.
+func (c *ManualClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.now
+}
+
+// AfterFunc schedules f to be called once the clock has been advanced by d.
+// If d isn't positive, f is called by the next call to Advance.
+func (c *ManualClock) AfterFunc(d time.Duration, f func()) Timer {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	t := &manualTimer{clock: c, at: c.now.Add(d), f: f}
+	c.timers = append(c.timers, t)
+	return t
+}
+
+// Advance moves the clock forward by d, calling the functions of the timers
+// expiring meanwhile in order, with the clock set to their expiry time. The
+// functions are called synchronously, without holding the clock's lock, so
+// they may schedule further timers, which also fire if they expire within d.
+func (c *ManualClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	end := c.now.Add(d)
+	for {
+		sort.SliceStable(c.timers, func(i, j int) bool {
+			return c.timers[i].at.Before(c.timers[j].at)
+		})
+		if len(c.timers) == 0 || c.timers[0].at.After(end) {
+			break
+		}
+
+		t := c.timers[0]
+		c.timers = c.timers[1:]
+		if t.at.After(c.now) {
+			c.now = t.at
+		}
+
+		c.mu.Unlock()
+		t.f()
+		c.mu.Lock()
+	}
+	c.now = end
+	c.mu.Unlock()
+}
+
+type manualTimer struct {
+	clock *ManualClock
+	at    time.Time
+	f     func()
+}
+
+func (t *manualTimer) Stop() bool {
+	c := t.clock
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for i, timer := range c.timers {
+		if timer == t {
+			c.timers = append(c.timers[:i], c.timers[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,81 @@
+package debounce_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/qpoint-io/debounce"
+)
+
+func TestManualClock(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := debounce.NewManualClock(start)
+
+	var fired []time.Duration
+	record := func() {
+		fired = append(fired, clock.Now().Sub(start))
+	}
+
+	clock.AfterFunc(2*time.Second, record)
+	clock.AfterFunc(time.Second, func() {
+		record()
+		// Timers scheduled while advancing fire within the same advance.
+		clock.AfterFunc(500*time.Millisecond, record)
+	})
+	stopped := clock.AfterFunc(1500*time.Millisecond, record)
+
+	if !stopped.Stop() {
+		t.Error("expected the timer to be active")
+	}
+	if stopped.Stop() {
+		t.Error("expected the timer to be stopped already")
+	}
+
+	clock.Advance(1800 * time.Millisecond)
+
+	want := []time.Duration{time.Second, 1500 * time.Millisecond}
+	if len(fired) != len(want) || fired[0] != want[0] || fired[1] != want[1] {
+		t.Errorf("expected timers to fire at %v, fired at %v", want, fired)
+	}
+	if now := clock.Now().Sub(start); now != 1800*time.Millisecond {
+		t.Errorf("expected the clock to be advanced by 1.8s, was %s", now)
+	}
+
+	clock.Advance(time.Second)
+	if len(fired) != 3 || fired[2] != 2*time.Second {
+		t.Errorf("expected the last timer to fire at 2s, fired at %v", fired)
+	}
+}
+
+func TestWithTimerClock(t *testing.T) {
+	clock := debounce.NewManualClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+
+	callCount := 0
+	f := func() {
+		callCount++
+	}
+
+	d := debounce.NewDebouncer(time.Minute, 1000, debounce.WithTimerClock(clock))
+	for i := 0; i < 10; i++ {
+		d.Do(f)
+		clock.Advance(50 * time.Second)
+	}
+	if callCount != 0 {
+		t.Errorf("expected 0 calls, got %d", callCount)
+	}
+
+	clock.Advance(10 * time.Second)
+	if callCount != 1 {
+		t.Errorf("expected 1 call, got %d", callCount)
+	}
+
+	// The max duration fires even though the interval never passes.
+	dd := debounce.NewDurationDebouncer(time.Minute, 3*time.Minute, debounce.WithTimerClock(clock))
+	for i := 0; i < 5; i++ {
+		dd.Do(f)
+		clock.Advance(50 * time.Second)
+	}
+	if callCount != 2 {
+		t.Errorf("expected 2 calls, got %d", callCount)
+	}
+}
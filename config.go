@@ -0,0 +1,114 @@
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package debounce
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Config describes a debouncer declaratively, e.g. in a service's JSON
+// configuration. See NewFromConfig.
+//
+// In JSON, durations may be given as strings accepted by time.ParseDuration,
+// such as "500ms", or as a number of nanoseconds.
+type Config struct {
+	// After is the quiet period after which the pending function is
+	// executed. It must be positive.
+	After time.Duration `json:"after"`
+
+	// MaxWait, if positive, bounds how long a burst can delay the execution.
+	// It must be at least After.
+	MaxWait time.Duration `json:"maxWait,omitempty"`
+
+	// CountLimit, if positive, executes the pending function once it has
+	// been called that many times. It can't be combined with Leading.
+	CountLimit uint64 `json:"countLimit,omitempty"`
+
+	// Leading executes f on the first call of a burst.
+	Leading bool `json:"leading,omitempty"`
+
+	// Trailing executes the last function at the end of a burst. At least
+	// one of Leading and Trailing must be set.
+	Trailing bool `json:"trailing,omitempty"`
+}
+
+// NewFromConfig returns a CountDebouncer configured by c, or an error
+// describing why c is invalid.
+func NewFromConfig(c Config) (Debouncer, error) {
+	if err := c.validate(); err != nil {
+		return nil, err
+	}
+
+	d := NewDebouncer(c.After, c.CountLimit, WithMaxWait(c.MaxWait))
+	d.leading = c.Leading
+	d.trailing = c.Leading && c.Trailing
+	return d, nil
+}
+
+func (c Config) validate() error {
+	switch {
+	case c.After <= 0:
+		return fmt.Errorf("debounce: after must be positive, got %s", c.After)
+	case c.MaxWait < 0:
+		return fmt.Errorf("debounce: maxWait must not be negative, got %s", c.MaxWait)
+	case c.MaxWait > 0 && c.MaxWait < c.After:
+		return fmt.Errorf("debounce: maxWait (%s) must not be shorter than after (%s)", c.MaxWait, c.After)
+	case !c.Leading && !c.Trailing:
+		return errors.New("debounce: at least one of leading and trailing must be set")
+	case c.Leading && c.CountLimit > 0:
+		return fmt.Errorf("debounce: countLimit (%d) is not supported with leading, which executes f on the first call of a burst", c.CountLimit)
+	}
+	return nil
+}
+
+// UnmarshalJSON implements json.Unmarshaler, accepting durations as strings
+// as well as numbers of nanoseconds.
+func (c *Config) UnmarshalJSON(data []byte) error {
+	type plain Config
+	var raw struct {
+		plain
+		After   json.RawMessage `json:"after"`
+		MaxWait json.RawMessage `json:"maxWait"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	after, err := parseDuration("after", raw.After)
+	if err != nil {
+		return err
+	}
+	maxWait, err := parseDuration("maxWait", raw.MaxWait)
+	if err != nil {
+		return err
+	}
+
+	*c = Config(raw.plain)
+	c.After, c.MaxWait = after, maxWait
+	return nil
+}
+
+func parseDuration(field string, data json.RawMessage) (time.Duration, error) {
+	if len(data) == 0 || string(data) == "null" {
+		return 0, nil
+	}
+
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		var n int64
+		if err := json.Unmarshal(data, &n); err != nil {
+			return 0, fmt.Errorf("debounce: %s must be a duration string or a number of nanoseconds, got %s", field, data)
+		}
+		return time.Duration(n), nil
+	}
+
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, fmt.Errorf("debounce: invalid %s: %w", field, err)
+	}
+	return d, nil
+}
@@ -0,0 +1,99 @@
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package debounce_test
+
+import (
+	"encoding/json"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/qpoint-io/debounce"
+)
+
+func TestNewFromConfig(t *testing.T) {
+	for _, tt := range []struct {
+		name   string
+		config debounce.Config
+		err    string
+	}{
+		{"Trailing", debounce.Config{After: time.Second, Trailing: true}, ""},
+		{"Leading", debounce.Config{After: time.Second, Leading: true}, ""},
+		{"MaxWait", debounce.Config{After: time.Second, MaxWait: time.Minute, CountLimit: 10, Trailing: true}, ""},
+		{"NoAfter", debounce.Config{Trailing: true}, "after must be positive"},
+		{"NegativeMaxWait", debounce.Config{After: time.Second, MaxWait: -1, Trailing: true}, "maxWait must not be negative"},
+		{"ShortMaxWait", debounce.Config{After: time.Second, MaxWait: time.Millisecond, Trailing: true}, "must not be shorter than after"},
+		{"NoEdge", debounce.Config{After: time.Second}, "at least one of leading and trailing"},
+		{"LeadingCountLimit", debounce.Config{After: time.Second, CountLimit: 2, Leading: true}, "countLimit (2) is not supported with leading"},
+		{"LeadingMaxWait", debounce.Config{After: time.Second, MaxWait: time.Minute, Leading: true, Trailing: true}, ""},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			d, err := debounce.NewFromConfig(tt.config)
+			if tt.err == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if d == nil {
+					t.Fatal("expected a debouncer")
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("expected error containing %q, got %v", tt.err, err)
+			}
+		})
+	}
+}
+
+func TestNewFromConfigEdges(t *testing.T) {
+	for _, tt := range []struct {
+		name              string
+		leading, trailing bool
+		now, later        int32
+	}{
+		{"Trailing", false, true, 0, 1},
+		{"Leading", true, false, 1, 1},
+		{"LeadingTrailing", true, true, 1, 2},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			var counter atomic.Int32
+			f := func() {
+				counter.Add(1)
+			}
+
+			d, err := debounce.NewFromConfig(debounce.Config{After: 20 * time.Millisecond, Leading: tt.leading, Trailing: tt.trailing})
+			if err != nil {
+				t.Fatal(err)
+			}
+			for i := 0; i < 3; i++ {
+				d.Do(f)
+			}
+			if n := counter.Load(); n != tt.now {
+				t.Errorf("expected %d calls right away, got %d", tt.now, n)
+			}
+			time.Sleep(50 * time.Millisecond)
+			if n := counter.Load(); n != tt.later {
+				t.Errorf("expected %d calls in total, got %d", tt.later, n)
+			}
+		})
+	}
+}
+
+func TestConfigUnmarshalJSON(t *testing.T) {
+	var c debounce.Config
+	err := json.Unmarshal([]byte(`{"after": "250ms", "maxWait": 2000000000, "countLimit": 5, "trailing": true}`), &c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := debounce.Config{After: 250 * time.Millisecond, MaxWait: 2 * time.Second, CountLimit: 5, Trailing: true}
+	if c != expected {
+		t.Errorf("expected %+v, got %+v", expected, c)
+	}
+
+	err = json.Unmarshal([]byte(`{"after": "soon"}`), &c)
+	if err == nil || !strings.Contains(err.Error(), "invalid after") {
+		t.Errorf("expected an invalid after error, got %v", err)
+	}
+}
@@ -0,0 +1,65 @@
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package debounce
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// NewCountMap returns a CountMap which counts the calls to Inc for each key
+// and passes the counts to handler once Inc stops being called for the given
+// duration, e.g. to batch metrics increments into a single write.
+//
+// handler owns the map it is passed; subsequent calls are counted into a new
+// map.
+func NewCountMap[K comparable](after time.Duration, handler func(counts map[K]int), opts ...Option) *CountMap[K] {
+	m := &CountMap[K]{
+		d:       NewDebouncer(after, math.MaxUint64, unclosable(opts)...),
+		handler: handler,
+	}
+	m.run = m.flush
+
+	return m
+}
+
+// A CountMap counts occurrences per key over a debounced window.
+// See NewCountMap.
+type CountMap[K comparable] struct {
+	mu      sync.Mutex
+	d       *CountDebouncer
+	counts  map[K]int
+	handler func(map[K]int)
+	run     func()
+}
+
+// Inc increments the count of key and restarts the window.
+func (m *CountMap[K]) Inc(key K) {
+	m.mu.Lock()
+	if m.counts == nil {
+		m.counts = make(map[K]int)
+	}
+	m.counts[key]++
+	m.mu.Unlock()
+
+	m.d.Do(m.run)
+}
+
+// Flush passes the counts collected so far, if any, to the handler right
+// away.
+func (m *CountMap[K]) Flush() {
+	m.d.Flush()
+}
+
+func (m *CountMap[K]) flush() {
+	m.mu.Lock()
+	counts := m.counts
+	m.counts = nil
+	m.mu.Unlock()
+
+	if len(counts) > 0 {
+		m.handler(counts)
+	}
+}
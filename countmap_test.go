@@ -0,0 +1,40 @@
+package debounce_test
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/qpoint-io/debounce"
+)
+
+func TestCountMap(t *testing.T) {
+	clock := debounce.NewManualClock(time.Now())
+
+	var flushed []map[string]int
+	handler := func(counts map[string]int) {
+		flushed = append(flushed, counts)
+	}
+
+	m := debounce.NewCountMap(time.Second, handler, debounce.WithTimerClock(clock))
+
+	for _, key := range []string{"a", "b", "a", "c", "a"} {
+		m.Inc(key)
+	}
+	clock.Advance(time.Second)
+	if want := []map[string]int{{"a": 3, "b": 1, "c": 1}}; !reflect.DeepEqual(flushed, want) {
+		t.Errorf("Expected counts %v, was %v", want, flushed)
+	}
+
+	// The counts start over, without touching the map passed to handler.
+	m.Inc("b")
+	m.Flush()
+	if want := []map[string]int{{"a": 3, "b": 1, "c": 1}, {"b": 1}}; !reflect.DeepEqual(flushed, want) {
+		t.Errorf("Expected counts %v, was %v", want, flushed)
+	}
+
+	m.Flush()
+	if len(flushed) != 2 {
+		t.Errorf("Expected no flush without counts, was %v", flushed)
+	}
+}
@@ -6,70 +6,1514 @@
 // Package debounce provides a debouncer func. The most typical use case would be
 // the user typing a text into a form; the UI needs an update, but let's wait for
 // a break.
+//
+// A pending call holds on to a runtime timer, which keeps the debouncer and
+// the pending function reachable until the timer fires, even if the
+// debouncer itself is no longer referenced. Short-lived debouncers that may
+// still have a call pending should be closed with Close once they're no
+// longer needed, which stops the timer and releases them right away.
 package debounce
 
 import (
+	"context"
+	"math"
+	"math/rand/v2"
+	"reflect"
+	"slices"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
 // New returns a debounced function that takes another function as its argument.
 // This function will be called when the debounced function stops being called
-// for the given duration, provided the maximum count hasn't been exceeded.
-// Once the maximum count is exceeded, the function is executed one last time
-// and the debouncer is reset.
+// for the given duration, provided the maximum count hasn't been reached.
+// Once the debounced function has been called countLimit times in a burst,
+// i.e. on the countLimit-th call, the function is executed right away and the
+// debouncer is reset. A countLimit of zero means no limit.
 // The debounced function can be invoked with different functions, if needed,
 // the last one will win.
-func New(after time.Duration, countLimit uint64) func(f func()) {
-	d := &debouncer{
-		after:      after,
-		countLimit: countLimit,
+func New(after time.Duration, countLimit uint64, opts ...Option) func(f func()) {
+	return NewDebouncer(after, countLimit, unclosable(opts)...).Do
+}
+
+// Wrap is like New, but always debounces the same function f, so the returned
+// function takes no argument.
+func Wrap(after time.Duration, countLimit uint64, f func()) func() {
+	d := NewDebouncer(after, countLimit)
+	return func() {
+		d.Do(f)
 	}
+}
+
+// NewWithCount is like New, but f is passed the number of calls of the burst
+// it was executed for. See CountDebouncer.DoCount.
+func NewWithCount(after time.Duration, countLimit uint64, opts ...Option) func(f func(count uint64)) {
+	return NewDebouncer(after, countLimit, unclosable(opts)...).DoCount
+}
+
+// NewWithMaxWait is like New, but f is executed no later than maxWait after the
+// first call of a burst, even if the debounced function keeps being called.
+func NewWithMaxWait(after, maxWait time.Duration, countLimit uint64, opts ...Option) func(f func()) {
+	return NewDebouncer(after, countLimit, unclosable(append([]Option{WithMaxWait(maxWait)}, opts...))...).Do
+}
+
+// NewCountOrDuration returns a debounced function which executes f either
+// when it stops being called for the given interval or once the count limit
+// is reached, whichever comes first. Both conditions are reset together, so
+// an expiring interval can never execute f again right after the count limit
+// did.
+func NewCountOrDuration(interval time.Duration, countLimit uint64, opts ...Option) func(f func()) {
+	return NewDebouncer(interval, countLimit, unclosable(opts)...).Do
+}
+
+// NewLeading returns a debounced function that executes f immediately on the
+// first call of a burst. Subsequent calls are ignored until the debounced
+// function stops being called for the given duration.
+func NewLeading(after time.Duration) func(f func()) {
+	d := NewDebouncer(after, math.MaxUint64)
+	d.leading = true
+	return d.Do
+}
+
+// NewLeadingTrailing returns a debounced function that executes f immediately
+// on the first call of a burst and, if it was called again during the burst,
+// once more with the last function when the debounced function stops being
+// called for the given duration.
+func NewLeadingTrailing(after time.Duration) func(f func()) {
+	d := NewDebouncer(after, math.MaxUint64)
+	d.leading = true
+	d.trailing = true
+	return d.Do
+}
+
+// NewImmediate returns a debounced function that executes f synchronously on
+// the caller's goroutine if no burst is in progress and reports true.
+// Otherwise, f is scheduled to be executed once the debounced function stops
+// being called for the given duration, the last function winning, and false
+// is reported.
+func NewImmediate(after time.Duration) func(f func()) (ranNow bool) {
+	d := NewDebouncer(after, math.MaxUint64)
+	d.leading = true
+	d.trailing = true
+	return d.DoFunc
+}
 
-	return func(f func()) {
-		d.add(f)
+// NewOnce returns a debounced function with the same semantics as New without
+// a count limit, except that it ignores all calls once a function has been
+// executed, e.g. to run a callback once initialization events stop arriving.
+func NewOnce(after time.Duration, opts ...Option) func(f func()) {
+	return NewDebouncer(after, math.MaxUint64, unclosable(append([]Option{WithOnce()}, opts...))...).Do
+}
+
+// NewWithError is like New, but f may fail. The returned onError function
+// registers a handler which is called with any non-nil error returned by f.
+// The handler is called without holding the debouncer's lock.
+func NewWithError(after time.Duration, countLimit uint64) (debounced func(f func() error), onError func(handler func(error))) {
+	var (
+		mu      sync.Mutex
+		handler func(error)
+	)
+
+	d := NewDebouncer(after, countLimit)
+
+	debounced = func(f func() error) {
+		d.Do(func() {
+			err := f()
+			if err == nil {
+				return
+			}
+
+			mu.Lock()
+			h := handler
+			mu.Unlock()
+
+			if h != nil {
+				h(err)
+			}
+		})
+	}
+
+	onError = func(h func(error)) {
+		mu.Lock()
+		defer mu.Unlock()
+		handler = h
 	}
+
+	return debounced, onError
+}
+
+// NewContext is like New, but once ctx is done the pending call, if any, is
+// dropped and subsequent calls to the debounced function are no-ops.
+func NewContext(ctx context.Context, after time.Duration, countLimit uint64) func(f func()) {
+	d := NewDebouncer(after, countLimit)
+	context.AfterFunc(ctx, d.Close)
+
+	return d.Do
 }
 
-type debouncer struct {
-	mu         sync.Mutex
-	after      time.Duration
-	timer      *time.Timer
-	count      uint64
-	countLimit uint64
+// NewContextFunc is like NewContext without a count limit, but f is passed a
+// child context of ctx, so that it can be aborted once ctx is done, e.g. on
+// shutdown. The child context is canceled when f returns.
+func NewContextFunc(ctx context.Context, after time.Duration) func(f func(context.Context)) {
+	debounced := NewContext(ctx, after, math.MaxUint64)
+
+	return func(f func(context.Context)) {
+		debounced(func() {
+			ctx, cancel := context.WithCancel(ctx)
+			defer cancel()
+
+			f(ctx)
+		})
+	}
+}
+
+// NewDebouncer returns a CountDebouncer with the same semantics as New. Unlike
+// the bare function returned by New, a CountDebouncer can also be canceled or
+// flushed.
+func NewDebouncer(after time.Duration, countLimit uint64, opts ...Option) *CountDebouncer {
+	return NewWithOptions(append([]Option{WithAfter(after), WithCountLimit(countLimit)}, opts...)...)
+}
+
+// NewWithOptions returns a CountDebouncer configured by the given options.
+func NewWithOptions(opts ...Option) *CountDebouncer {
+	c := newConfig(opts)
+
+	d := &CountDebouncer{
+		name:          c.name,
+		registry:      c.registry,
+		now:           c.now,
+		afterFunc:     c.afterFunc,
+		after:         c.after,
+		maxWait:       c.maxWait,
+		maxJitter:     c.maxJitter,
+		rand:          c.rand,
+		backoffFactor: c.backoffFactor,
+		maxInterval:   c.maxInterval,
+		adaptiveMin:   c.adaptiveMin,
+		adaptiveMax:   c.adaptiveMax,
+		countLimit:    c.countLimit,
+		minCount:      c.minCount,
+		leading:       c.leading,
+		skipIdentical: c.skipIdentical,
+		noOverlap:     c.noOverlap,
+		once:          c.once,
+		rateLimit:     c.rateLimit,
+		ratePer:       c.ratePer,
+		cooldown:      c.cooldown,
+		executor:      c.executor,
+		dispatcher:    c.dispatcher,
+	}
+	d.idle = sync.NewCond(&d.mu)
+	d.closedCh = make(chan struct{})
+	d.weight = 1
+	// Until calls are observed, assume they are sparse.
+	d.gapAverage = c.adaptiveMax
+	d.trigger = d.runCallback
+	if c.reusableTimer {
+		d.engine = newTimerEngine(d.fire)
+	}
+	if d.registry != nil {
+		d.registry.add(d)
+	}
+
+	return d
+}
+
+// CountDebouncer is a count-limited debouncer. See New for the semantics of Do.
+type CountDebouncer struct {
+	mu        sync.Mutex
+	name      string
+	registry  *Registry
+	now       func() time.Time
+	afterFunc func(time.Duration, func()) Timer
+
+	after         time.Duration
+	maxWait       time.Duration
+	maxJitter     time.Duration
+	rand          *rand.Rand
+	backoffFactor float64
+	maxInterval   time.Duration
+	adaptiveMin   time.Duration
+	adaptiveMax   time.Duration
+	countLimit    uint64
+	minCount      uint64
+	leading       bool
+	trailing      bool
+	throttle      bool
+	skipIdentical bool
+	noOverlap     bool
+	once          bool
+	rateLimit     int
+	ratePer       time.Duration
+	cooldown      time.Duration
+	executor      func(task func())
+	dispatcher    func(task func())
+
+	// callback is the function set by SetCallback, which trigger calls.
+	// trigger is created once, so that Trigger doesn't allocate.
+	callback func()
+	trigger  func()
+
+	onPanic    func(any)
+	onExecute  func()
+	onCoalesce func()
+	onLatency  func(time.Duration)
+	onArm      func()
+
+	// onIdle, if set, is called without holding mu when the timer ends a
+	// burst, whether it executed f or dropped the burst. Keyed uses it to
+	// release the key.
+	onIdle func()
+
+	// interval is the current quiet period, which grows with backoff.
+	// armed and count are only written while holding mu, but are atomic so
+	// that Pending and Count can read them without it.
+	interval time.Duration
+	timer    Timer
+	engine   *timerEngine
+	armed    atomic.Bool
+	deadline time.Time
+	paused   bool
+	gen      uint64
+	start    time.Time
+	count    atomic.Uint64
+	closed   bool
+
+	// lastCall is the time of the last call and gapAverage the moving
+	// average of the time between calls, if adaptive.
+	lastCall   time.Time
+	gapAverage time.Duration
+
+	// backstop is the timer armed by FlushAfter, expiring at backstopAt.
+	backstop   Timer
+	backstopAt time.Time
+
+	lastExecution time.Time
+	lastLatency   time.Duration
+
+	// executions are the times f was executed within the last ratePer, if
+	// rate limited.
+	executions []time.Time
+
+	// pending is the last function passed to Do. The timer executes
+	// whatever is pending when it fires rather than the function it was
+	// armed for, which is what makes the last function win. It is cleared
+	// by reset, so that an idle debouncer doesn't keep it reachable.
+	pending func()
+
+	// within overrides the quiet period while a call to DoWithin is armed,
+	// which hasWithin reports, as a zero within is valid too.
+	within    time.Duration
+	hasWithin bool
+
+	// weight is added to the count by the current call, 1 unless it is
+	// made by DoWeighted.
+	weight uint64
+
+	// submission is incremented by every call, see Token.
+	submission uint64
+
+	// countTo, if set by DoCount, receives the count of the burst when the
+	// pending function is taken.
+	countTo *uint64
+
+	// running is set while f is executed with WithNoOverlap, deferred if
+	// the pending function is to be executed once it returns.
+	running        bool
+	deferred       bool
+	deferredReason FireReason
+
+	// lastReason is the reason the last function was executed for.
+	lastReason FireReason
+
+	// inFlight are the times the functions being executed were started.
+	inFlight []time.Time
+
+	// fired, if set, is closed once the next function has been executed,
+	// see WaitForNextFire.
+	fired chan struct{}
+
+	// closedCh is closed by Close, CloseFlush and WithOnce, see Done.
+	closedCh chan struct{}
+
+	// busy is the number of armed bursts and running functions, idle is
+	// signaled when it drops to zero.
+	busy int
+	idle *sync.Cond
+
+	executed  atomic.Uint64
+	coalesced atomic.Uint64
+	dropped   atomic.Uint64
+}
+
+// Do schedules f to be called once the debouncer has been quiet for the
+// configured duration, or immediately if the count limit is reached.
+// f is always executed without holding the debouncer's lock, so it may call
+// back into the debouncer.
+//
+// f may be nil to start or extend the burst before the function to execute
+// is known; the function of a later call is executed then. If none was
+// passed by the time the debouncer fires, nothing is executed.
+func (d *CountDebouncer) Do(f func()) {
+	d.DoFunc(f)
+}
+
+// DoFunc is like Do, but reports whether this call executed f synchronously,
+// e.g. because it reached the count limit, rather than just scheduling it.
+func (d *CountDebouncer) DoFunc(f func()) bool {
+	run, hook := d.add(f)
+	if hook != nil {
+		hook()
+	}
+	if run == nil {
+		return false
+	}
+
+	d.execute(run)
+	return true
+}
+
+// SetCallback sets the function executed by Trigger. It may be changed at any
+// time; a pending trigger executes the callback set when it fires.
+func (d *CountDebouncer) SetCallback(f func()) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.callback = f
+}
+
+// Trigger is like Do with the function set by SetCallback, without passing a
+// closure on every call. If no callback is set by the time the debouncer
+// fires, nothing is executed.
+func (d *CountDebouncer) Trigger() {
+	d.Do(d.trigger)
+}
+
+func (d *CountDebouncer) runCallback() {
+	d.mu.Lock()
+	f := d.callback
+	d.mu.Unlock()
+
+	if f != nil {
+		f()
+	}
+}
+
+// TryDo is like Do, but only if no invocation is pending and no cooldown is
+// in effect, see WithCooldown. Otherwise, f is dropped, the pending invocation
+// is left as scheduled and false is returned.
+// Unlike Do, it never pushes the execution out, so that many producers can
+// make sure something is executed soon without starving it.
+func (d *CountDebouncer) TryDo(f func()) bool {
+	d.mu.Lock()
+	if d.armed.Load() || d.closed || d.dropCooldown() {
+		d.mu.Unlock()
+		return false
+	}
+	run, hook := d.addLocked(f)
+	d.mu.Unlock()
+
+	if hook != nil {
+		hook()
+	}
+	if run != nil {
+		d.execute(run)
+	}
+	return true
+}
+
+// A Token identifies a call made with DoC.
+type Token struct {
+	d          *CountDebouncer
+	submission uint64
+}
+
+// Cancel drops the pending invocation, like CountDebouncer.Cancel, but only
+// if f of the call identified by the token is still the pending function. If
+// a later call superseded it, or it has been executed already, Cancel is a
+// no-op. It reports whether the invocation was dropped.
+func (t Token) Cancel() bool {
+	d := t.d
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.submission != t.submission || d.pending == nil {
+		return false
+	}
+	d.reset()
+	return true
+}
+
+// DoC is like Do, but returns a token to cancel this very call.
+func (d *CountDebouncer) DoC(f func()) Token {
+	d.mu.Lock()
+	run, hook := d.addLocked(f)
+	t := Token{d: d, submission: d.submission}
+	d.mu.Unlock()
+
+	if hook != nil {
+		hook()
+	}
+	if run != nil {
+		d.execute(run)
+	}
+	return t
+}
+
+// DoWithin is like Do, but the quiet period is after for this call only,
+// e.g. for an occasional urgent call. Subsequent calls to Do revert to the
+// configured quiet period. If after isn't positive, the timer fires right
+// away.
+func (d *CountDebouncer) DoWithin(after time.Duration, f func()) {
+	d.mu.Lock()
+	d.within, d.hasWithin = after, true
+	run, hook := d.addLocked(f)
+	d.within, d.hasWithin = 0, false
+	d.mu.Unlock()
+
+	if hook != nil {
+		hook()
+	}
+	if run != nil {
+		d.execute(run)
+	}
+}
+
+// DoWeighted is like Do, but the call counts weight times toward the count
+// limit, e.g. for a bulk import that is worth many edits. f is executed right
+// away once the weights of the burst add up to the count limit. Do is
+// DoWeighted with a weight of one.
+func (d *CountDebouncer) DoWeighted(weight uint64, f func()) {
+	d.mu.Lock()
+	d.weight = weight
+	run, hook := d.addLocked(f)
+	d.weight = 1
+	d.mu.Unlock()
+
+	if hook != nil {
+		hook()
+	}
+	if run != nil {
+		d.execute(run)
+	}
+}
+
+// DoCount is like Do, but f is passed the number of calls of the burst it
+// was executed for, e.g. to log how many rapid edits were flushed at once.
+// Calls made with DoWeighted count as many calls as their weight.
+func (d *CountDebouncer) DoCount(f func(count uint64)) {
+	var count uint64
+
+	d.mu.Lock()
+	d.countTo = &count
+	run, hook := d.addLocked(func() {
+		f(count)
+	})
+	d.mu.Unlock()
+
+	if hook != nil {
+		hook()
+	}
+	if run != nil {
+		d.execute(run)
+	}
 }
 
-func (d *debouncer) add(f func()) {
+// add registers f and returns the function to execute right away, if any,
+// and the hook to call if a burst was started or a call was coalesced.
+func (d *CountDebouncer) add(f func()) (run, hook func()) {
 	d.mu.Lock()
 	defer d.mu.Unlock()
 
-	// Increment the count
-	d.count++
+	return d.addLocked(f)
+}
+
+// addLocked is add for callers holding d.mu.
+func (d *CountDebouncer) addLocked(f func()) (run, hook func()) {
+	armed := d.armed.Load()
+	run, hook = d.register(f)
+	if !armed && d.armed.Load() && d.onArm != nil {
+		hook = d.onArm
+	}
+	return run, hook
+}
+
+// register registers f and returns the function to execute right away, if
+// any, and the hook to call if a call was coalesced.
+func (d *CountDebouncer) register(f func()) (run, onCoalesce func()) {
+	if d.closed {
+		return nil, nil
+	}
+
+	d.submission++
+	if d.dropCooldown() {
+		return nil, nil
+	}
+	if d.adaptiveMax > 0 {
+		d.observeCall()
+	}
+
+	// Increment the count, saturating rather than wrapping around.
+	if count := d.count.Load(); count > math.MaxUint64-d.weight {
+		d.count.Store(math.MaxUint64)
+	} else {
+		d.count.Store(count + d.weight)
+	}
+	if d.leading {
+		return d.addLeading(f)
+	}
 
-	// If count exceeds maxCount, execute the function and reset
-	if d.count > d.countLimit {
-		if d.timer != nil {
-			d.timer.Stop()
-			d.timer = nil
+	identical := d.skipIdentical && d.armed.Load() && sameFunc(d.pending, f)
+	if f != nil {
+		d.pending = f
+	}
+
+	if d.armed.Load() {
+		onCoalesce = d.coalesce()
+	}
+
+	// If count reaches the limit, execute the function and reset. While
+	// paused, it is executed on Resume instead.
+	if d.count.Load() >= d.countLimit && !d.paused {
+		wait := d.rateWait()
+		if wait == 0 {
+			// Reset the count for the next iteration
+			return d.take(FireCountLimit), onCoalesce
 		}
+		// Rate limited, fire as soon as allowed instead.
+		d.arm()
+		d.deadline = d.now().Add(wait)
+		d.startTimer(wait)
+		return nil, onCoalesce
+	}
+
+	if identical {
+		// Let the timer run.
+		return nil, onCoalesce
+	}
+
+	d.arm()
+
+	return nil, onCoalesce
+}
+
+// dropCooldown reports whether a call is to be dropped because it was made
+// within the cooldown of an execution, counting it if so.
+func (d *CountDebouncer) dropCooldown() bool {
+	if d.cooldown <= 0 || d.lastExecution.IsZero() || d.now().Sub(d.lastExecution) >= d.cooldown {
+		return false
+	}
+	d.dropped.Add(1)
+	return true
+}
+
+// sameFunc reports whether f and g share the same code pointer.
+func sameFunc(f, g func()) bool {
+	if f == nil || g == nil {
+		return false
+	}
+	return reflect.ValueOf(f).Pointer() == reflect.ValueOf(g).Pointer()
+}
+
+// addLeading returns f if no burst is in progress and (re)arms the quiet
+// period that has to pass before f can be executed again. In trailing mode,
+// calls made during the burst are executed once the burst is over. When
+// throttling, calls made during the burst don't extend it.
+func (d *CountDebouncer) addLeading(f func()) (run, onCoalesce func()) {
+	switch {
+	case d.armed.Load():
+		onCoalesce = d.coalesce()
+		if d.trailing && f != nil {
+			d.pending = f
+		}
+		if d.throttle {
+			return nil, onCoalesce
+		}
+	case d.paused:
+		// Execute f once resumed.
+		d.pending = f
+	default:
+		count := d.count.Load()
+		d.pending = f
+		run = d.take(FireLeading)
+		if d.closed {
+			// Closed by WithOnce, there is no burst to start.
+			return run, nil
+		}
+		// The call executed right away still counts towards the burst it
+		// starts.
+		d.count.Store(count)
+	}
+
+	d.arm()
+
+	return run, onCoalesce
+}
+
+// coalesce records that a call has been coalesced and returns the hook to call.
+func (d *CountDebouncer) coalesce() func() {
+	d.coalesced.Add(1)
+	return d.onCoalesce
+}
+
+// arm (re)starts the timer, making sure it doesn't expire later than the max
+// wait from the start of the burst. While paused, only the deadline is set.
+func (d *CountDebouncer) arm() {
+	now := d.now()
+	if d.armed.Load() {
+		d.backoff()
+	} else {
+		d.armed.Store(true)
+		d.busy++
+		d.start = now
+		d.interval = d.after
+	}
+	if d.adaptiveMax > 0 {
+		d.interval = d.adaptiveInterval()
+	}
+
+	after := d.interval + d.jitter()
+	if d.hasWithin {
+		after = max(d.within, 0)
+	}
+	if d.maxWait > 0 {
+		remaining := max(d.maxWait-now.Sub(d.start), 0)
+		after = min(after, remaining)
+	}
+	d.deadline = now.Add(after)
+
+	if !d.paused {
+		d.startTimer(after)
+	}
+}
+
+// startTimer (re)starts the timer to fire after the given duration.
+func (d *CountDebouncer) startTimer(after time.Duration) {
+	if d.timer != nil {
+		d.timer.Stop()
+	}
+
+	d.gen++
+	if d.engine != nil {
+		d.engine.reset(after, d.gen)
+		d.timer = d.engine
+		return
+	}
+
+	gen := d.gen
+	d.timer = d.afterFunc(after, func() {
+		d.fire(gen)
+	})
+}
+
+// backoff grows the interval by the backoff factor, up to the max interval
+// but never below the quiet period.
+func (d *CountDebouncer) backoff() {
+	if d.backoffFactor <= 1 {
+		return
+	}
+	d.interval = max(d.after, min(time.Duration(float64(d.interval)*d.backoffFactor), d.maxInterval))
+}
+
+// observeCall folds the time since the previous call into the moving average
+// of the time between calls.
+func (d *CountDebouncer) observeCall() {
+	now := d.now()
+	if !d.lastCall.IsZero() {
+		gap := now.Sub(d.lastCall)
+		d.gapAverage += (gap - d.gapAverage) / 4
+	}
+	d.lastCall = now
+}
+
+// adaptiveInterval returns the quiet period for the average time between
+// calls, see WithAdaptive.
+func (d *CountDebouncer) adaptiveInterval() time.Duration {
+	return min(max(d.adaptiveMin+d.adaptiveMax-d.gapAverage, d.adaptiveMin), d.adaptiveMax)
+}
+
+// jitter returns a random duration in [0, d.maxJitter).
+func (d *CountDebouncer) jitter() time.Duration {
+	if d.maxJitter <= 0 {
+		return 0
+	}
+	if d.rand != nil {
+		return time.Duration(d.rand.Int64N(int64(d.maxJitter)))
+	}
+	return rand.N(d.maxJitter)
+}
+
+// take resets the debouncer and returns the pending function, if any, which
+// must then be passed to execute. reason is recorded as the reason for the
+// execution.
+func (d *CountDebouncer) take(reason FireReason) func() {
+	if d.running && d.pending != nil {
+		// Execute the pending function once the running one returns.
+		d.deferred = true
+		d.deferredReason = reason
+		return nil
+	}
+
+	f, count, countTo, start := d.pending, d.count.Load(), d.countTo, d.start
+	d.reset()
+	if f != nil {
+		if countTo != nil {
+			*countTo = count
+		}
+		d.executed.Add(1)
+		d.lastReason = reason
+		d.running = d.noOverlap
+		if d.once && !d.closed {
+			d.closed = true
+			d.closeDone()
+			d.release()
+		}
+		d.lastExecution = d.now()
+		d.lastLatency = 0
+		if !start.IsZero() {
+			d.lastLatency = d.lastExecution.Sub(start)
+		}
+		if d.onLatency != nil {
+			f = withLatency(f, d.onLatency, d.lastLatency)
+		}
+		if d.rateLimit > 0 {
+			d.executions = append(d.executions, d.lastExecution)
+		}
+		d.busy++
+	}
+	return f
+}
+
+// withLatency returns a function executing f and then passing latency to hook.
+func withLatency(f func(), hook func(time.Duration), latency time.Duration) func() {
+	return func() {
+		f()
+		hook(latency)
+	}
+}
+
+// rateWait returns how long f has to wait before it may be executed without
+// exceeding the rate limit, forgetting executions which left the window.
+func (d *CountDebouncer) rateWait() time.Duration {
+	if d.rateLimit <= 0 {
+		return 0
+	}
+
+	now := d.now()
+	i := 0
+	for i < len(d.executions) && now.Sub(d.executions[i]) >= d.ratePer {
+		i++
+	}
+	d.executions = append(d.executions[:0], d.executions[i:]...)
+
+	if len(d.executions) < d.rateLimit {
+		return 0
+	}
+	return d.executions[len(d.executions)-d.rateLimit].Add(d.ratePer).Sub(now)
+}
+
+// fire is called when the timer armed as generation gen expires and executes
+// the pending function. If the debouncer has been rearmed or reset since, the
+// timer was stopped too late and fire is a no-op.
+func (d *CountDebouncer) fire(gen uint64) {
+	d.mu.Lock()
+	if gen != d.gen {
+		d.mu.Unlock()
+		return
+	}
+	if !d.leading && d.count.Load() < d.minCount {
+		// Too few calls in this burst, drop it.
+		d.reset()
+		onIdle := d.onIdle
+		d.mu.Unlock()
+
+		if onIdle != nil {
+			onIdle()
+		}
+		return
+	}
+	if wait := d.rateWait(); wait > 0 {
+		// Rate limited, try again once allowed.
+		d.deadline = d.now().Add(wait)
+		d.startTimer(wait)
+		d.mu.Unlock()
+		return
+	}
+	// Reset the count before the function is executed
+	f := d.take(d.fireReason())
+	if f != nil && d.throttle && d.trailing && !d.closed {
+		// The trailing execution starts the next interval, keeping the
+		// cadence steady under sustained load.
+		d.arm()
+	}
+	var onIdle func()
+	if !d.armed.Load() {
+		onIdle = d.onIdle
+	}
+	d.mu.Unlock()
+
+	if onIdle != nil {
+		onIdle()
+	}
+	if f != nil {
+		d.dispatch(f)
+	}
+}
+
+// fireReason returns the reason the timer fired for.
+func (d *CountDebouncer) fireReason() FireReason {
+	switch {
+	case d.count.Load() >= d.countLimit:
+		// Reached while paused or rate limited.
+		return FireCountLimit
+	case d.maxWait > 0 && d.now().Sub(d.start) >= d.maxWait:
+		return FireMaxWait
+	default:
+		return FireTrailing
+	}
+}
+
+// dispatch executes f on behalf of a timer, through the executor if any.
+func (d *CountDebouncer) dispatch(f func()) {
+	if d.executor != nil {
+		d.executor(func() {
+			d.execute(f)
+		})
+		return
+	}
+	d.execute(f)
+}
+
+// Cancel drops the pending invocation, if any, without executing it.
+// The debouncer can be used again afterwards.
+func (d *CountDebouncer) Cancel() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
 
+	d.reset()
+}
+
+// CancelIf is like Cancel, but only drops the pending invocation if pred
+// reports true for the time elapsed since the first call of the burst,
+// according to the debouncer's clock, e.g. to abort only early in the window.
+// It reports whether the invocation was dropped. pred is called while holding
+// the debouncer's lock, so it must not call back into the debouncer.
+func (d *CountDebouncer) CancelIf(pred func(elapsed time.Duration) bool) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if !d.armed.Load() || !pred(d.now().Sub(d.start)) {
+		return false
+	}
+	d.reset()
+	return true
+}
+
+// Reset clears the state of the current burst, dropping the pending
+// invocation, if any, without executing it. It is equivalent to Cancel and
+// is provided for callers reusing a debouncer across separate batches.
+func (d *CountDebouncer) Reset() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.reset()
+}
+
+// Flush stops the timer and executes the pending function, if any, right away.
+func (d *CountDebouncer) Flush() {
+	d.mu.Lock()
+	f := d.take(FireFlush)
+	d.mu.Unlock()
+
+	if f != nil {
+		d.execute(f)
+	}
+}
+
+// FlushDone is like Flush, but executes the pending function on a new
+// goroutine and returns a channel which is closed once it has returned, e.g.
+// to wait for the flush on shutdown alongside other events. If nothing is
+// pending, the returned channel is already closed. If the execution is
+// deferred by WithNoOverlap, the channel is closed once the debouncer is idle.
+func (d *CountDebouncer) FlushDone() <-chan struct{} {
+	done := make(chan struct{})
+
+	d.mu.Lock()
+	f := d.take(FireFlush)
+	deferred := d.deferred
+	d.mu.Unlock()
+
+	if f == nil && !deferred {
+		close(done)
+		return done
+	}
+
+	if f == nil {
+		go func() {
+			defer close(done)
+			d.Wait()
+		}()
+		return done
+	}
+
+	go d.execute(func() {
+		defer close(done)
 		f()
+	})
+	return done
+}
 
-		// Reset the count for the next iteration
-		d.count = 0
+// FlushAfter guarantees that the pending invocation, if any, is executed no
+// later than after from now, as if Flush was called then, even if the
+// debounced function keeps being called. If the pending invocation is
+// executed earlier, the guarantee is lifted. If FlushAfter is called again
+// for the same invocation, the earliest guarantee applies.
+func (d *CountDebouncer) FlushAfter(after time.Duration) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if !d.armed.Load() {
 		return
 	}
 
+	at := d.now().Add(after)
+	if !d.backstopAt.IsZero() {
+		if !at.Before(d.backstopAt) {
+			return
+		}
+		d.stopBackstop()
+	}
+	d.backstopAt = at
+
+	// While paused, the backstop is started by Resume.
+	if !d.paused {
+		d.startBackstop(after)
+	}
+}
+
+// startBackstop starts the timer of the guarantee made by FlushAfter.
+func (d *CountDebouncer) startBackstop(after time.Duration) {
+	var backstop Timer
+	backstop = d.afterFunc(after, func() {
+		d.mu.Lock()
+		if d.backstop != backstop {
+			// Stopped too late.
+			d.mu.Unlock()
+			return
+		}
+		f := d.take(FireFlush)
+		d.mu.Unlock()
+
+		if f != nil {
+			d.dispatch(f)
+		}
+	})
+	d.backstop = backstop
+}
+
+// stopBackstop stops the timer of the guarantee made by FlushAfter, if
+// running, leaving backstopAt as is.
+func (d *CountDebouncer) stopBackstop() {
+	if d.backstop != nil {
+		d.backstop.Stop()
+		d.backstop = nil
+	}
+}
+
+// Pending reports whether an invocation is scheduled but has not fired yet.
+// It doesn't take the debouncer's lock, so it can be polled frequently.
+func (d *CountDebouncer) Pending() bool {
+	return d.armed.Load()
+}
+
+// Count returns the number of calls of the burst in progress, like the
+// CurrentCount of Stats. It doesn't take the debouncer's lock, so it can be
+// polled frequently.
+func (d *CountDebouncer) Count() uint64 {
+	return d.count.Load()
+}
+
+// Peek returns the pending function, if any, without executing or dropping
+// it.
+func (d *CountDebouncer) Peek() (func(), bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return d.pending, d.pending != nil
+}
+
+// Deadline returns the time the pending invocation is scheduled for, and
+// whether one is scheduled at all, e.g. to process whichever of several
+// debouncers is due first. While paused, nothing is scheduled.
+func (d *CountDebouncer) Deadline() (time.Time, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if !d.armed.Load() || d.paused {
+		return time.Time{}, false
+	}
+	return d.deadline, true
+}
+
+// TimeUntilFire returns how long until the pending invocation is executed and
+// whether one is scheduled at all. While paused, nothing is scheduled.
+func (d *CountDebouncer) TimeUntilFire() (time.Duration, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if !d.armed.Load() || d.paused {
+		return 0, false
+	}
+	return max(d.deadline.Sub(d.now()), 0), true
+}
+
+// Reschedule pushes the pending execution out by extra, without registering
+// a call, e.g. when more related calls are known to be imminent. The max wait
+// still applies. It is a no-op if no execution is scheduled.
+func (d *CountDebouncer) Reschedule(extra time.Duration) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if !d.armed.Load() {
+		return
+	}
+
+	now := d.now()
+	after := max(d.deadline.Sub(now)+extra, 0)
+	if d.maxWait > 0 {
+		after = min(after, max(d.maxWait-now.Sub(d.start), 0))
+	}
+	d.deadline = now.Add(after)
+
+	if !d.paused {
+		d.startTimer(after)
+	}
+}
+
+// Interval returns the quiet period the next call would wait for, which
+// differs from the configured one with WithBackoff or WithAdaptive.
+func (d *CountDebouncer) Interval() time.Duration {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	switch {
+	case d.adaptiveMax > 0:
+		return d.adaptiveInterval()
+	case d.armed.Load():
+		return d.interval
+	default:
+		return d.after
+	}
+}
+
+// SetAfter changes the quiet period. The new duration applies from the next
+// call to Do on; a timer that is already running isn't rearmed.
+func (d *CountDebouncer) SetAfter(after time.Duration) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.after = after
+	d.interval = after
+}
+
+// Pause stops the timer without dropping the pending function. Calls made
+// while paused are registered as usual, the last function still winning, but
+// nothing is executed until Resume is called, including by FlushAfter.
+func (d *CountDebouncer) Pause() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.paused {
+		return
+	}
+
+	d.paused = true
 	if d.timer != nil {
 		d.timer.Stop()
+		d.timer = nil
+		d.gen++
+	}
+	d.stopBackstop()
+}
+
+// Resume restarts the timer stopped by Pause for the remainder of the quiet
+// period. If the quiet period elapsed, the count limit was reached or the
+// guarantee of FlushAfter expired while paused, the pending function is
+// executed right away.
+func (d *CountDebouncer) Resume() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if !d.paused {
+		return
+	}
+
+	d.paused = false
+	if !d.armed.Load() {
+		return
+	}
+
+	remaining := max(d.deadline.Sub(d.now()), 0)
+	if d.count.Load() >= d.countLimit {
+		remaining = 0
+	}
+	d.startTimer(remaining)
+	if !d.backstopAt.IsZero() {
+		d.startBackstop(max(d.backstopAt.Sub(d.now()), 0))
+	}
+}
+
+// Name returns the name of the debouncer set by WithName, if any.
+func (d *CountDebouncer) Name() string {
+	return d.name
+}
+
+// OnPanic registers a handler which is called with the recovered value if a
+// debounced function panics. Without a handler, the panic is propagated.
+// Either way, the debouncer remains usable.
+func (d *CountDebouncer) OnPanic(handler func(any)) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.onPanic = handler
+}
+
+// OnExecute registers a hook which is called every time a debounced function
+// has been executed. The hook is called without holding the debouncer's lock.
+func (d *CountDebouncer) OnExecute(hook func()) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.onExecute = hook
+}
+
+// OnExecuteWithLatency registers a hook which is called every time a
+// debounced function has been executed, with the time between the first call
+// of its burst and its execution, e.g. to feed a histogram when tuning the
+// quiet period. The hook is called without holding the debouncer's lock.
+func (d *CountDebouncer) OnExecuteWithLatency(hook func(latency time.Duration)) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.onLatency = hook
+}
+
+// OnArm registers a hook which is called once at the start of every burst,
+// when a call arms the timer of an idle debouncer, e.g. to show that changes
+// are pending. The hook is called without holding the debouncer's lock.
+func (d *CountDebouncer) OnArm(hook func()) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.onArm = hook
+}
+
+// OnCoalesce registers a hook which is called every time a call is coalesced
+// with another one, i.e. when a pending call is superseded by a later call.
+// It is not called for the first call of a burst. The hook is called without
+// holding the debouncer's lock.
+func (d *CountDebouncer) OnCoalesce(hook func()) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.onCoalesce = hook
+}
+
+// Stats is a snapshot of a debouncer's statistics.
+type Stats struct {
+	// Executions is the number of times a function has been executed.
+	Executions uint64
+
+	// Coalesced is the number of calls that were superseded by a later call.
+	Coalesced uint64
+
+	// LastExecution is the time a function was last executed, or the zero
+	// time if none has been executed yet.
+	LastExecution time.Time
+
+	// CurrentCount is the number of calls in the current burst.
+	CurrentCount uint64
+
+	// LastLatency is the time between the first call of the burst a
+	// function was last executed for and its execution.
+	LastLatency time.Duration
+
+	// Dropped is the number of calls dropped during a cooldown, see
+	// WithCooldown.
+	Dropped uint64
+}
+
+// Stats returns a consistent snapshot of the debouncer's statistics.
+func (d *CountDebouncer) Stats() Stats {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return Stats{
+		Executions:    d.executed.Load(),
+		Coalesced:     d.coalesced.Load(),
+		LastExecution: d.lastExecution,
+		CurrentCount:  d.count.Load(),
+		LastLatency:   d.lastLatency,
+		Dropped:       d.dropped.Load(),
 	}
-	d.timer = time.AfterFunc(d.after, func() {
+}
+
+// Burst returns a consistent snapshot of the burst in progress: when its
+// first call was made, how many calls it has absorbed so far and whether an
+// execution is scheduled, e.g. for logging. All are zero when idle.
+func (d *CountDebouncer) Burst() (start time.Time, count uint64, active bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return d.start, d.count.Load(), d.armed.Load()
+}
+
+// LastFireReason returns why the last function was executed, or FireNone if
+// none has been executed yet.
+func (d *CountDebouncer) LastFireReason() FireReason {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return d.lastReason
+}
+
+// LastFired returns the time a function was last executed, according to the
+// debouncer's clock, or the zero time if none has been executed yet.
+func (d *CountDebouncer) LastFired() time.Time {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return d.lastExecution
+}
+
+// IsStuck reports whether a function has been executing for longer than
+// threshold according to the debouncer's clock, e.g. because it hung, so that
+// a readiness probe can report the debouncer as unhealthy.
+func (d *CountDebouncer) IsStuck(threshold time.Duration) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	now := d.now()
+	for _, started := range d.inFlight {
+		if now.Sub(started) > threshold {
+			return true
+		}
+	}
+	return false
+}
+
+// Executed returns the number of times a function has been executed.
+func (d *CountDebouncer) Executed() uint64 {
+	return d.executed.Load()
+}
+
+// Coalesced returns the number of calls that were superseded by a later call
+// and therefore never executed on their own.
+func (d *CountDebouncer) Coalesced() uint64 {
+	return d.coalesced.Load()
+}
+
+// Close drops the pending invocation, if any, releases the timer and removes
+// the debouncer from its registry, if any.
+// Subsequent calls to Do are no-ops. Close may be called more than once.
+func (d *CountDebouncer) Close() {
+	d.mu.Lock()
+	d.closed = true
+	d.reset()
+	d.closeDone()
+	d.mu.Unlock()
+
+	d.release()
+}
+
+// CloseFlush is like Close, but executes the pending function, if any, right
+// away before returning, so that the last call isn't lost on shutdown.
+//
+// With WithNoOverlap, if a function is still being executed, the pending
+// function is instead executed as soon as it returns, and CloseFlush doesn't
+// wait for it, as it may be called from that function. Use Wait to wait for
+// it.
+func (d *CountDebouncer) CloseFlush() {
+	d.mu.Lock()
+	f := d.take(FireFlush)
+	if d.deferred {
+		// The timer must not fire the deferred function a second time.
+		d.disarm()
+	}
+	d.closed = true
+	d.closeDone()
+	d.mu.Unlock()
+
+	d.release()
+	if f != nil {
+		d.execute(f)
+	}
+}
+
+// Done returns a channel which is closed once the debouncer is closed with
+// Close or CloseFlush, or by WithOnce, like the Done channel of a
+// context.Context, e.g. so that goroutines feeding the debouncer can return.
+func (d *CountDebouncer) Done() <-chan struct{} {
+	return d.closedCh
+}
+
+// closeDone closes d.closedCh unless it is already closed.
+func (d *CountDebouncer) closeDone() {
+	select {
+	case <-d.closedCh:
+	default:
+		close(d.closedCh)
+	}
+}
+
+// release stops the timer goroutine, if any, and removes the debouncer from its
+// registry, if any, once it is closed. Both only take their own lock, so
+// release may be called with d.mu held.
+func (d *CountDebouncer) release() {
+	if d.engine != nil {
+		d.engine.close()
+	}
+	if d.registry != nil {
+		d.registry.remove(d)
+	}
+}
+
+// Wait blocks until the pending invocation, if any, has been executed and
+// any function that is currently being executed has returned. Combined with
+// Flush, it executes the pending function right away and waits for it.
+// Wait must not be called from a debounced function.
+func (d *CountDebouncer) Wait() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for d.busy > 0 {
+		d.idle.Wait()
+	}
+}
+
+// WaitForNextFire blocks until the next function executed by the debouncer
+// has returned, or until ctx is done, in which case it returns ctx.Err(). It
+// is mostly useful in tests, to wait for a debounced function without
+// sleeping for a guessed duration.
+func (d *CountDebouncer) WaitForNextFire(ctx context.Context) error {
+	d.mu.Lock()
+	if d.fired == nil {
+		d.fired = make(chan struct{})
+	}
+	fired := d.fired
+	d.mu.Unlock()
+
+	select {
+	case <-fired:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// execute runs f, which must be called without holding d.mu, followed by
+// the function deferred meanwhile by WithNoOverlap, if any. With
+// WithDispatch, they are handed off to the dispatcher instead.
+func (d *CountDebouncer) execute(f func()) {
+	if d.dispatcher != nil {
+		d.dispatcher(func() {
+			d.executeAll(f)
+		})
+		return
+	}
+	d.executeAll(f)
+}
+
+// executeAll is execute on the current goroutine.
+func (d *CountDebouncer) executeAll(f func()) {
+	for f != nil {
+		d.executeOne(f)
+		f = d.takeDeferred()
+	}
+}
+
+// takeDeferred returns the pending function if its execution was deferred
+// by WithNoOverlap.
+func (d *CountDebouncer) takeDeferred() func() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if !d.deferred {
+		return nil
+	}
+	d.deferred = false
+	return d.take(d.deferredReason)
+}
+
+func (d *CountDebouncer) executeOne(f func()) {
+	d.mu.Lock()
+	onPanic, onExecute := d.onPanic, d.onExecute
+	started := d.now()
+	d.inFlight = append(d.inFlight, started)
+	d.mu.Unlock()
+
+	defer func() {
 		d.mu.Lock()
-		defer d.mu.Unlock()
+		if i := slices.Index(d.inFlight, started); i >= 0 {
+			d.inFlight = slices.Delete(d.inFlight, i, i+1)
+		}
+		if d.fired != nil {
+			close(d.fired)
+			d.fired = nil
+		}
+		d.running = false
+		d.done()
+		d.mu.Unlock()
+	}()
 
-		f()
+	call(f, onPanic)
+	if onExecute != nil {
+		onExecute()
+	}
+}
 
-		// Reset the count after the function is executed
-		d.count = 0
-	})
+func (d *CountDebouncer) done() {
+	d.busy--
+	if d.busy == 0 {
+		d.idle.Broadcast()
+	}
+}
+
+func (d *CountDebouncer) reset() {
+	d.disarm()
+	d.count.Store(0)
+	d.start = time.Time{}
+	d.deadline = time.Time{}
+	d.pending = nil
+	d.countTo = nil
+	d.deferred = false
+}
+
+// disarm stops the timers, keeping the pending function.
+func (d *CountDebouncer) disarm() {
+	d.gen++
+	if d.timer != nil {
+		d.timer.Stop()
+		d.timer = nil
+	}
+	d.stopBackstop()
+	d.backstopAt = time.Time{}
+	if d.armed.Load() {
+		d.armed.Store(false)
+		d.done()
+	}
+}
+
+// call executes f, passing any panic on to onPanic if set.
+func call(f func(), onPanic func(any)) {
+	if onPanic != nil {
+		defer func() {
+			if r := recover(); r != nil {
+				onPanic(r)
+			}
+		}()
+	}
+
+	f()
 }
@@ -1,7 +1,11 @@
 package debounce_test
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"math"
+	"runtime"
 	"sync"
 	"sync/atomic"
 	"testing"
@@ -174,3 +178,1965 @@ func TestDebouncerCountLimit(t *testing.T) {
 		t.Errorf("Expected function to be executed %d time(s), but got %d", expectedExecCount, atomic.LoadUint64(&execCount))
 	}
 }
+
+func TestDebouncerCountLimitFirePoints(t *testing.T) {
+	tests := []struct {
+		countLimit uint64
+		// ranNow is the expected result of DoFunc for each call.
+		ranNow []bool
+	}{
+		// Zero means no limit, so nothing is executed before the timer fires.
+		{countLimit: 0, ranNow: []bool{false, false, false, false, false}},
+		{countLimit: 1, ranNow: []bool{true, true, true}},
+		{countLimit: 2, ranNow: []bool{false, true, false, true}},
+		{countLimit: 3, ranNow: []bool{false, false, true, false, false, true}},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("limit %d", tt.countLimit), func(t *testing.T) {
+			d := debounce.NewDebouncer(time.Hour, tt.countLimit)
+			for i, want := range tt.ranNow {
+				if got := d.DoFunc(func() {}); got != want {
+					t.Errorf("Expected call %d to return %t, was %t", i+1, want, got)
+				}
+			}
+			d.Cancel()
+		})
+	}
+}
+
+func TestDebounceCancel(t *testing.T) {
+	var counter uint64
+
+	f := func() {
+		atomic.AddUint64(&counter, 1)
+	}
+
+	d := debounce.NewDebouncer(50*time.Millisecond, 1000)
+
+	// Canceling an idle debouncer is a no-op.
+	d.Cancel()
+
+	for i := 0; i < 10; i++ {
+		d.Do(f)
+	}
+	d.Cancel()
+
+	time.Sleep(100 * time.Millisecond)
+
+	if c := atomic.LoadUint64(&counter); c != 0 {
+		t.Error("Expected count 0, was", c)
+	}
+
+	// The debouncer is reusable after a cancel.
+	d.Do(f)
+
+	time.Sleep(100 * time.Millisecond)
+
+	if c := atomic.LoadUint64(&counter); c != 1 {
+		t.Error("Expected count 1, was", c)
+	}
+}
+
+func TestDebounceCancelConcurrent(t *testing.T) {
+	var wg sync.WaitGroup
+
+	d := debounce.NewDebouncer(50*time.Millisecond, 1000)
+
+	for i := 0; i < 10; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			d.Do(func() {})
+		}()
+		go func() {
+			defer wg.Done()
+			d.Cancel()
+		}()
+	}
+	wg.Wait()
+	d.Cancel()
+}
+
+func TestDebounceFlush(t *testing.T) {
+	var (
+		counter1 uint64
+		counter2 uint64
+	)
+
+	f1 := func() {
+		atomic.AddUint64(&counter1, 1)
+	}
+
+	f2 := func() {
+		atomic.AddUint64(&counter2, 1)
+	}
+
+	d := debounce.NewDebouncer(time.Hour, 1000)
+
+	// Flushing an idle debouncer is a no-op.
+	d.Flush()
+
+	d.Do(f1)
+	d.Do(f2)
+	d.Flush()
+
+	if c := atomic.LoadUint64(&counter1); c != 0 {
+		t.Error("Expected count 0, was", c)
+	}
+	if c := atomic.LoadUint64(&counter2); c != 1 {
+		t.Error("Expected count 1, was", c)
+	}
+
+	// Nothing is left pending after a flush.
+	d.Flush()
+
+	if c := atomic.LoadUint64(&counter2); c != 1 {
+		t.Error("Expected count 1, was", c)
+	}
+}
+
+func TestDebouncePending(t *testing.T) {
+	d := debounce.NewDebouncer(50*time.Millisecond, 1000)
+
+	if d.Pending() {
+		t.Error("Expected no pending invocation")
+	}
+
+	d.Do(func() {})
+
+	if !d.Pending() {
+		t.Error("Expected a pending invocation")
+	}
+
+	time.Sleep(100 * time.Millisecond)
+
+	if d.Pending() {
+		t.Error("Expected no pending invocation after firing")
+	}
+}
+
+func TestDebounceLeading(t *testing.T) {
+	var counter uint64
+
+	f := func() {
+		atomic.AddUint64(&counter, 1)
+	}
+
+	debounced := debounce.NewLeading(50 * time.Millisecond)
+
+	for i := 0; i < 3; i++ {
+		for j := 0; j < 10; j++ {
+			debounced(f)
+
+			// The first call of the burst executes right away.
+			if c := int(atomic.LoadUint64(&counter)); c != i+1 {
+				t.Fatalf("Expected count %d, was %d", i+1, c)
+			}
+		}
+
+		time.Sleep(100 * time.Millisecond)
+	}
+
+	if c := atomic.LoadUint64(&counter); c != 3 {
+		t.Error("Expected count 3, was", c)
+	}
+}
+
+func TestDebounceLeadingTrailing(t *testing.T) {
+	var (
+		counter1 uint64
+		counter2 uint64
+	)
+
+	f1 := func() {
+		atomic.AddUint64(&counter1, 1)
+	}
+
+	f2 := func() {
+		atomic.AddUint64(&counter2, 1)
+	}
+
+	debounced := debounce.NewLeadingTrailing(50 * time.Millisecond)
+
+	// A single call only fires on the leading edge.
+	debounced(f1)
+	time.Sleep(100 * time.Millisecond)
+
+	if c := atomic.LoadUint64(&counter1); c != 1 {
+		t.Error("Expected count 1, was", c)
+	}
+
+	// A burst fires on both edges, the last function wins on the trailing edge.
+	debounced(f1)
+	for i := 0; i < 10; i++ {
+		debounced(f2)
+	}
+
+	if c := atomic.LoadUint64(&counter1); c != 2 {
+		t.Error("Expected count 2, was", c)
+	}
+	if c := atomic.LoadUint64(&counter2); c != 0 {
+		t.Error("Expected count 0, was", c)
+	}
+
+	time.Sleep(100 * time.Millisecond)
+
+	if c := atomic.LoadUint64(&counter1); c != 2 {
+		t.Error("Expected count 2, was", c)
+	}
+	if c := atomic.LoadUint64(&counter2); c != 1 {
+		t.Error("Expected count 1, was", c)
+	}
+}
+
+func TestDebounceImmediate(t *testing.T) {
+	var (
+		counter1 uint64
+		counter2 uint64
+	)
+
+	f1 := func() {
+		atomic.AddUint64(&counter1, 1)
+	}
+
+	f2 := func() {
+		atomic.AddUint64(&counter2, 1)
+	}
+
+	debounced := debounce.NewImmediate(50 * time.Millisecond)
+
+	// The first call runs inline, the rest of the burst on the trailing edge.
+	if !debounced(f1) {
+		t.Error("Expected the first call to run now")
+	}
+	if c := atomic.LoadUint64(&counter1); c != 1 {
+		t.Error("Expected count 1, was", c)
+	}
+
+	for i := 0; i < 10; i++ {
+		if debounced(f2) {
+			t.Error("Expected subsequent calls to be debounced")
+		}
+	}
+	if c := atomic.LoadUint64(&counter2); c != 0 {
+		t.Error("Expected count 0, was", c)
+	}
+
+	time.Sleep(100 * time.Millisecond)
+
+	if c := atomic.LoadUint64(&counter2); c != 1 {
+		t.Error("Expected count 1, was", c)
+	}
+
+	// Once the burst is over, the debouncer is cold again.
+	if !debounced(f1) {
+		t.Error("Expected the first call after the burst to run now")
+	}
+	if c := atomic.LoadUint64(&counter1); c != 2 {
+		t.Error("Expected count 2, was", c)
+	}
+}
+
+func TestWrap(t *testing.T) {
+	var counter uint64
+
+	debounced := debounce.Wrap(50*time.Millisecond, 1000, func() {
+		atomic.AddUint64(&counter, 1)
+	})
+
+	for i := 0; i < 10; i++ {
+		debounced()
+	}
+	time.Sleep(100 * time.Millisecond)
+
+	if c := atomic.LoadUint64(&counter); c != 1 {
+		t.Error("Expected count 1, was", c)
+	}
+}
+
+func TestDebounceWithCount(t *testing.T) {
+	clock := debounce.NewManualClock(time.Now())
+
+	var counts []uint64
+	f := func(count uint64) {
+		counts = append(counts, count)
+	}
+
+	debounced := debounce.NewWithCount(time.Second, 5, debounce.WithTimerClock(clock))
+
+	for i := 0; i < 3; i++ {
+		debounced(f)
+	}
+	clock.Advance(time.Second)
+
+	// Reaching the count limit executes f right away.
+	for i := 0; i < 5; i++ {
+		debounced(f)
+	}
+
+	if len(counts) != 2 || counts[0] != 3 || counts[1] != 5 {
+		t.Errorf("expected counts [3 5], got %v", counts)
+	}
+}
+
+func TestDebounceDoWeighted(t *testing.T) {
+	clock := debounce.NewManualClock(time.Now())
+
+	var calls []string
+	record := func(name string) func() {
+		return func() {
+			calls = append(calls, name)
+		}
+	}
+
+	d := debounce.NewDebouncer(time.Second, 10, debounce.WithTimerClock(clock))
+
+	// Two light calls and a heavy one reach the limit of ten.
+	d.Do(record("a"))
+	d.Do(record("b"))
+	d.DoWeighted(8, record("bulk"))
+	if len(calls) != 1 || calls[0] != "bulk" {
+		t.Fatalf("expected the heavy call to execute f right away, got %v", calls)
+	}
+
+	// The count was reset, so a lighter burst waits for the quiet period.
+	d.DoWeighted(5, record("c"))
+	d.Do(record("d"))
+	if s := d.Stats(); s.CurrentCount != 6 {
+		t.Errorf("expected a count of 6, got %d", s.CurrentCount)
+	}
+	clock.Advance(time.Second)
+	if len(calls) != 2 || calls[1] != "d" {
+		t.Errorf("expected [bulk d], got %v", calls)
+	}
+
+	// Weights saturate rather than wrap around.
+	unlimited := debounce.NewDebouncer(time.Second, 0, debounce.WithTimerClock(clock))
+	unlimited.DoWeighted(math.MaxUint64-1, record("e"))
+	unlimited.DoWeighted(2, record("f"))
+	if len(calls) != 3 || calls[2] != "f" {
+		t.Errorf("expected the saturated count to execute f, got %v", calls)
+	}
+}
+
+func TestDebounceIsStuck(t *testing.T) {
+	clock := debounce.NewManualClock(time.Now())
+
+	started, release := make(chan struct{}), make(chan struct{})
+	d := debounce.NewDebouncer(time.Second, 0, debounce.WithTimerClock(clock))
+
+	if d.IsStuck(0) {
+		t.Error("expected an idle debouncer not to be stuck")
+	}
+
+	d.Do(func() {
+		close(started)
+		<-release
+	})
+	go d.Flush()
+	<-started
+
+	clock.Advance(time.Minute)
+	if d.IsStuck(time.Hour) {
+		t.Error("expected f not to be stuck before the threshold")
+	}
+	if !d.IsStuck(30 * time.Second) {
+		t.Error("expected f to be stuck after the threshold")
+	}
+
+	close(release)
+	d.Wait()
+	if d.IsStuck(0) {
+		t.Error("expected the debouncer not to be stuck once f returned")
+	}
+}
+
+func TestDebounceSetCallback(t *testing.T) {
+	clock := debounce.NewManualClock(time.Now())
+
+	var calls []string
+	d := debounce.NewDebouncer(time.Second, 0, debounce.WithTimerClock(clock))
+
+	// Without a callback, triggering is a no-op.
+	d.Trigger()
+	clock.Advance(time.Second)
+	if len(calls) != 0 {
+		t.Errorf("expected no calls, got %v", calls)
+	}
+
+	d.SetCallback(func() {
+		calls = append(calls, "a")
+	})
+	for i := 0; i < 3; i++ {
+		d.Trigger()
+	}
+	clock.Advance(time.Second)
+
+	// A pending trigger executes the callback set when it fires.
+	d.Trigger()
+	d.SetCallback(func() {
+		calls = append(calls, "b")
+	})
+	clock.Advance(time.Second)
+
+	if len(calls) != 2 || calls[0] != "a" || calls[1] != "b" {
+		t.Errorf("expected calls [a b], got %v", calls)
+	}
+}
+
+func TestDebounceTryDo(t *testing.T) {
+	clock := debounce.NewManualClock(time.Now())
+
+	var calls []int
+	d := debounce.NewDebouncer(time.Minute, 1000, debounce.WithTimerClock(clock))
+
+	if !d.TryDo(func() { calls = append(calls, 1) }) {
+		t.Error("expected the first call to be scheduled")
+	}
+
+	// Later calls neither replace f nor push the execution out.
+	clock.Advance(30 * time.Second)
+	if d.TryDo(func() { calls = append(calls, 2) }) {
+		t.Error("expected the second call to be dropped")
+	}
+	clock.Advance(30 * time.Second)
+
+	if len(calls) != 1 || calls[0] != 1 {
+		t.Errorf("expected [1], got %v", calls)
+	}
+
+	if !d.TryDo(func() {}) {
+		t.Error("expected a call to be scheduled once the previous one fired")
+	}
+	d.Cancel()
+}
+
+func TestDebounceDoC(t *testing.T) {
+	var counter uint64
+
+	f := func() {
+		atomic.AddUint64(&counter, 1)
+	}
+
+	d := debounce.NewDebouncer(time.Hour, 1000)
+
+	// A superseded call can't cancel the later one.
+	first := d.DoC(f)
+	second := d.DoC(f)
+	if first.Cancel() {
+		t.Error("expected canceling a superseded call to be a no-op")
+	}
+	if !d.Pending() {
+		t.Error("expected a pending invocation")
+	}
+
+	if !second.Cancel() {
+		t.Error("expected the latest call to be canceled")
+	}
+	if d.Pending() {
+		t.Error("expected no pending invocation")
+	}
+
+	// Once executed, there is nothing left to cancel.
+	third := d.DoC(f)
+	d.Flush()
+	if third.Cancel() {
+		t.Error("expected canceling an executed call to be a no-op")
+	}
+	if c := atomic.LoadUint64(&counter); c != 1 {
+		t.Error("Expected count 1, was", c)
+	}
+}
+
+func TestDebounceNilFunc(t *testing.T) {
+	clock := debounce.NewManualClock(time.Now())
+
+	callCount := 0
+	d := debounce.NewDebouncer(time.Minute, 1000, debounce.WithTimerClock(clock))
+
+	// A nil function starts the burst, the function comes later.
+	d.Do(nil)
+	clock.Advance(30 * time.Second)
+	d.Do(func() {
+		callCount++
+	})
+	// And doesn't drop the pending function.
+	clock.Advance(30 * time.Second)
+	d.Do(nil)
+	clock.Advance(59 * time.Second)
+	if callCount != 0 {
+		t.Errorf("expected 0 calls, got %d", callCount)
+	}
+	clock.Advance(time.Second)
+	if callCount != 1 {
+		t.Errorf("expected 1 call, got %d", callCount)
+	}
+
+	// Without any function, firing is a no-op.
+	d.Do(nil)
+	clock.Advance(time.Minute)
+	if d.Pending() || d.Executed() != 1 {
+		t.Error("expected nothing to be executed")
+	}
+}
+
+func TestDebouncePeek(t *testing.T) {
+	var calls []string
+
+	d := debounce.NewDebouncer(time.Hour, 1000)
+	if _, ok := d.Peek(); ok {
+		t.Error("expected no pending function")
+	}
+
+	d.Do(func() { calls = append(calls, "first") })
+	d.Do(func() { calls = append(calls, "last") })
+
+	f, ok := d.Peek()
+	if !ok {
+		t.Fatal("expected a pending function")
+	}
+	f()
+	if len(calls) != 1 || calls[0] != "last" {
+		t.Errorf("expected the last function to be pending, got %v", calls)
+	}
+
+	// Peeking doesn't affect the pending invocation.
+	if !d.Pending() {
+		t.Error("expected a pending invocation")
+	}
+	d.Cancel()
+}
+
+func TestDebounceOnce(t *testing.T) {
+	var counter uint64
+
+	f := func() {
+		atomic.AddUint64(&counter, 1)
+	}
+
+	debounced := debounce.NewOnce(20 * time.Millisecond)
+
+	for i := 0; i < 3; i++ {
+		for j := 0; j < 10; j++ {
+			debounced(f)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+
+	if c := atomic.LoadUint64(&counter); c != 1 {
+		t.Error("Expected count 1, was", c)
+	}
+}
+
+func TestDebounceDoWithin(t *testing.T) {
+	clock := debounce.NewManualClock(time.Now())
+	d := debounce.NewDebouncer(time.Minute, 0, debounce.WithTimerClock(clock))
+
+	callCount := 0
+	f := func() {
+		callCount++
+	}
+
+	d.Do(f)
+	d.DoWithin(time.Second, f)
+	clock.Advance(time.Second)
+	if callCount != 1 {
+		t.Errorf("expected the urgent call to be executed, got %d calls", callCount)
+	}
+
+	// Subsequent calls use the default quiet period again.
+	d.DoWithin(time.Second, f)
+	d.Do(f)
+	clock.Advance(time.Second)
+	if callCount != 1 {
+		t.Errorf("expected 1 call, got %d", callCount)
+	}
+	clock.Advance(time.Minute)
+	if callCount != 2 {
+		t.Errorf("expected 2 calls, got %d", callCount)
+	}
+
+	// A zero or negative quiet period fires right away.
+	for i, after := range []time.Duration{0, -time.Second} {
+		d.Do(f)
+		d.DoWithin(after, f)
+		if remaining, ok := d.TimeUntilFire(); !ok || remaining != 0 {
+			t.Errorf("expected the timer to fire right away, got %s, %v", remaining, ok)
+		}
+		clock.Advance(0)
+		if callCount != 3+i {
+			t.Errorf("expected %d calls, got %d", 3+i, callCount)
+		}
+	}
+}
+
+func TestDebounceWithArg(t *testing.T) {
+	var (
+		mu   sync.Mutex
+		args []int
+	)
+
+	f := func(arg int) {
+		mu.Lock()
+		defer mu.Unlock()
+		args = append(args, arg)
+	}
+
+	debounced := debounce.NewWithArg[int](50*time.Millisecond, 1000)
+
+	for i := 0; i < 2; i++ {
+		for j := 1; j <= 5; j++ {
+			debounced(i*10+j, f)
+		}
+
+		time.Sleep(100 * time.Millisecond)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	if len(args) != 2 || args[0] != 5 || args[1] != 15 {
+		t.Error("Expected args [5 15], was", args)
+	}
+}
+
+func TestDebounceWithCoalesce(t *testing.T) {
+	type edit struct {
+		doc     string
+		version int
+	}
+
+	clock := debounce.NewManualClock(time.Now())
+	sameDoc := func(prev, next edit) bool {
+		return prev.doc == next.doc
+	}
+	debounced := debounce.NewWithCoalesce(time.Second, sameDoc, debounce.WithTimerClock(clock))
+
+	var saved []edit
+	save := func(e edit) {
+		saved = append(saved, e)
+	}
+
+	debounced(edit{"a", 1}, save)
+	debounced(edit{"a", 2}, save)
+
+	// An edit to another document flushes the previous one right away.
+	debounced(edit{"b", 1}, save)
+	if len(saved) != 1 || saved[0] != (edit{"a", 2}) {
+		t.Errorf("expected [{a 2}], got %v", saved)
+	}
+
+	debounced(edit{"b", 2}, save)
+	clock.Advance(time.Second)
+
+	if len(saved) != 2 || saved[1] != (edit{"b", 2}) {
+		t.Errorf("expected [{a 2} {b 2}], got %v", saved)
+	}
+}
+
+func TestDebounceResult(t *testing.T) {
+	clock := debounce.NewManualClock(time.Now())
+
+	trigger, last := debounce.NewResult[int](time.Second, debounce.WithTimerClock(clock))
+
+	if _, ok := last(); ok {
+		t.Error("expected no result before the first execution")
+	}
+
+	for i := 1; i <= 3; i++ {
+		trigger(func() int {
+			return i * i
+		})
+	}
+	if _, ok := last(); ok {
+		t.Error("expected no result before the quiet period")
+	}
+
+	clock.Advance(time.Second)
+	if r, ok := last(); !ok || r != 9 {
+		t.Errorf("expected 9, got %d, %v", r, ok)
+	}
+}
+
+func TestDebounceWithError(t *testing.T) {
+	var (
+		mu   sync.Mutex
+		errs []error
+	)
+
+	errFailed := errors.New("failed")
+
+	debounced, onError := debounce.NewWithError(50*time.Millisecond, 1000)
+
+	onError(func(err error) {
+		mu.Lock()
+		errs = append(errs, err)
+		mu.Unlock()
+
+		// The handler may call back into the debouncer.
+		debounced(func() error { return nil })
+	})
+
+	debounced(func() error { return errFailed })
+	time.Sleep(100 * time.Millisecond)
+
+	debounced(func() error { return nil })
+	time.Sleep(100 * time.Millisecond)
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	if len(errs) != 1 || errs[0] != errFailed {
+		t.Error("Expected errors [failed], was", errs)
+	}
+}
+
+func TestDebounceContext(t *testing.T) {
+	var counter uint64
+
+	f := func() {
+		atomic.AddUint64(&counter, 1)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	debounced := debounce.NewContext(ctx, 50*time.Millisecond, 1000)
+
+	debounced(f)
+	time.Sleep(100 * time.Millisecond)
+
+	if c := atomic.LoadUint64(&counter); c != 1 {
+		t.Error("Expected count 1, was", c)
+	}
+
+	// Canceling the context drops the pending call.
+	debounced(f)
+	cancel()
+	time.Sleep(100 * time.Millisecond)
+
+	// And the debounced function is a no-op afterwards.
+	debounced(f)
+	time.Sleep(100 * time.Millisecond)
+
+	if c := atomic.LoadUint64(&counter); c != 1 {
+		t.Error("Expected count 1, was", c)
+	}
+}
+
+func TestDebounceContextFunc(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	debounced := debounce.NewContextFunc(ctx, 10*time.Millisecond)
+
+	started := make(chan struct{})
+	aborted := make(chan error, 1)
+
+	debounced(func(ctx context.Context) {
+		close(started)
+		<-ctx.Done()
+		aborted <- ctx.Err()
+	})
+
+	<-started
+	// Canceling the construction context aborts the running call.
+	cancel()
+
+	select {
+	case err := <-aborted:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("expected context.Canceled, got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected the call to be aborted")
+	}
+}
+
+func ExampleNewDebouncer() {
+	var counter uint64
+
+	f := func() {
+		atomic.AddUint64(&counter, 1)
+	}
+
+	d := debounce.NewDebouncer(time.Hour, 1000)
+
+	for i := 0; i < 10; i++ {
+		d.Do(f)
+	}
+
+	fmt.Println("Pending:", d.Pending())
+
+	// Don't wait an hour, run the last function now.
+	d.Flush()
+
+	fmt.Println("Pending:", d.Pending())
+	fmt.Println("Counter is", atomic.LoadUint64(&counter))
+	// Output:
+	// Pending: true
+	// Pending: false
+	// Counter is 1
+}
+
+func TestDebounceCounters(t *testing.T) {
+	d := debounce.NewDebouncer(50*time.Millisecond, 5)
+
+	// Three calls coalesce into a single trailing execution.
+	for i := 0; i < 3; i++ {
+		d.Do(func() {})
+	}
+	time.Sleep(100 * time.Millisecond)
+
+	if n := d.Executed(); n != 1 {
+		t.Error("Expected 1 execution, was", n)
+	}
+	if n := d.Coalesced(); n != 2 {
+		t.Error("Expected 2 coalesced calls, was", n)
+	}
+
+	// Reaching the count limit executes immediately.
+	for i := 0; i < 5; i++ {
+		d.Do(func() {})
+	}
+
+	if n := d.Executed(); n != 2 {
+		t.Error("Expected 2 executions, was", n)
+	}
+	if n := d.Coalesced(); n != 6 {
+		t.Error("Expected 6 coalesced calls, was", n)
+	}
+}
+
+func TestDebounceOnPanic(t *testing.T) {
+	var recovered atomic.Value
+
+	d := debounce.NewDebouncer(50*time.Millisecond, 1000)
+	d.OnPanic(func(v any) {
+		recovered.Store(v)
+	})
+
+	d.Do(func() {
+		panic("boom")
+	})
+	time.Sleep(100 * time.Millisecond)
+
+	if v := recovered.Load(); v != "boom" {
+		t.Error("Expected recovered value boom, was", v)
+	}
+
+	// The debouncer remains usable after a panic.
+	var counter uint64
+	d.Do(func() {
+		atomic.AddUint64(&counter, 1)
+	})
+	time.Sleep(100 * time.Millisecond)
+
+	if c := atomic.LoadUint64(&counter); c != 1 {
+		t.Error("Expected count 1, was", c)
+	}
+}
+
+func TestDebouncePanicWithoutHandler(t *testing.T) {
+	d := debounce.NewDebouncer(time.Hour, 1000)
+	d.Do(func() {
+		panic("boom")
+	})
+
+	func() {
+		defer func() {
+			if r := recover(); r != "boom" {
+				t.Error("Expected panic boom, was", r)
+			}
+		}()
+		d.Flush()
+	}()
+
+	if d.Pending() {
+		t.Error("Expected no pending invocation after a panic")
+	}
+}
+
+func TestDebounceCancelIf(t *testing.T) {
+	clock := debounce.NewManualClock(time.Now())
+
+	var counter int
+	f := func() {
+		counter++
+	}
+	early := func(elapsed time.Duration) bool {
+		return elapsed < 10*time.Second
+	}
+
+	d := debounce.NewDebouncer(time.Minute, 0, debounce.WithTimerClock(clock))
+
+	if d.CancelIf(early) {
+		t.Error("expected nothing to cancel")
+	}
+
+	// Late in the burst, the invocation is kept.
+	d.Do(f)
+	clock.Advance(20 * time.Second)
+	d.Do(f)
+	if d.CancelIf(early) {
+		t.Error("expected the invocation to be kept late in the burst")
+	}
+	clock.Advance(time.Minute)
+	if counter != 1 {
+		t.Errorf("expected 1 call, got %d", counter)
+	}
+
+	// Early in the burst, it is dropped.
+	d.Do(f)
+	clock.Advance(5 * time.Second)
+	if !d.CancelIf(early) {
+		t.Error("expected the invocation to be dropped early in the burst")
+	}
+	clock.Advance(time.Minute)
+	if counter != 1 || d.Pending() {
+		t.Errorf("expected no more calls, got %d", counter)
+	}
+}
+
+func TestDebounceReset(t *testing.T) {
+	var counter uint64
+
+	f := func() {
+		atomic.AddUint64(&counter, 1)
+	}
+
+	d := debounce.NewDebouncer(50*time.Millisecond, 3)
+
+	d.Reset()
+
+	// Without the reset, the third call would reach the count limit.
+	for i := 0; i < 2; i++ {
+		d.Do(f)
+	}
+	d.Reset()
+	d.Reset()
+	d.Do(f)
+
+	if c := atomic.LoadUint64(&counter); c != 0 {
+		t.Error("Expected count 0, was", c)
+	}
+
+	time.Sleep(100 * time.Millisecond)
+
+	if c := atomic.LoadUint64(&counter); c != 1 {
+		t.Error("Expected count 1, was", c)
+	}
+}
+
+func TestDebounceClose(t *testing.T) {
+	var counter uint64
+
+	f := func() {
+		atomic.AddUint64(&counter, 1)
+	}
+
+	d := debounce.NewDebouncer(50*time.Millisecond, 1000)
+
+	d.Do(f)
+	d.Close()
+	d.Close()
+
+	if d.Pending() {
+		t.Error("Expected no pending invocation")
+	}
+
+	d.Do(f)
+	time.Sleep(100 * time.Millisecond)
+
+	if c := atomic.LoadUint64(&counter); c != 0 {
+		t.Error("Expected count 0, was", c)
+	}
+}
+
+func TestDebounceDone(t *testing.T) {
+	d := debounce.NewDebouncer(time.Hour, 0)
+
+	// A feeder exits once the debouncer is closed.
+	exited := make(chan struct{})
+	go func() {
+		defer close(exited)
+
+		ticker := time.NewTicker(time.Millisecond)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-d.Done():
+				return
+			case <-ticker.C:
+				d.Do(func() {})
+			}
+		}
+	}()
+
+	select {
+	case <-d.Done():
+		t.Fatal("expected Done to be open before Close")
+	default:
+	}
+
+	d.Close()
+	d.Close()
+	select {
+	case <-exited:
+	case <-time.After(time.Second):
+		t.Fatal("expected the feeder to exit once closed")
+	}
+
+	flushed := debounce.NewDebouncer(time.Hour, 0)
+	flushed.CloseFlush()
+	select {
+	case <-flushed.Done():
+	default:
+		t.Error("expected Done to be closed by CloseFlush")
+	}
+}
+
+func TestDebounceCloseFlush(t *testing.T) {
+	var counter uint64
+
+	f := func() {
+		atomic.AddUint64(&counter, 1)
+	}
+
+	d := debounce.NewDebouncer(50*time.Millisecond, 1000)
+
+	for i := 0; i < 10; i++ {
+		d.Do(f)
+	}
+	d.CloseFlush()
+
+	if c := atomic.LoadUint64(&counter); c != 1 {
+		t.Error("Expected count 1, was", c)
+	}
+	if d.Pending() {
+		t.Error("Expected no pending invocation")
+	}
+
+	// The debouncer is inert afterwards.
+	d.Do(f)
+	d.CloseFlush()
+	time.Sleep(100 * time.Millisecond)
+
+	if c := atomic.LoadUint64(&counter); c != 1 {
+		t.Error("Expected count 1, was", c)
+	}
+}
+
+func TestDebounceCloseFlushNoOverlap(t *testing.T) {
+	var counter atomic.Uint64
+	d := debounce.NewDebouncer(time.Second, 1000, debounce.WithNoOverlap())
+
+	// Start a long-running execution and queue another call behind it.
+	started, release := make(chan struct{}), make(chan struct{})
+	d.Do(func() {
+		close(started)
+		<-release
+		counter.Add(1)
+	})
+	go d.Flush()
+	<-started
+	d.Do(func() {
+		counter.Add(1)
+	})
+
+	d.CloseFlush()
+	if d.Pending() {
+		t.Error("Expected no pending invocation")
+	}
+
+	// The pending function is executed once the running one returns.
+	close(release)
+	d.Wait()
+	if c := counter.Load(); c != 2 {
+		t.Error("Expected count 2, was", c)
+	}
+}
+
+func TestDebounceWait(t *testing.T) {
+	var counter uint64
+
+	f := func() {
+		time.Sleep(50 * time.Millisecond)
+		atomic.AddUint64(&counter, 1)
+	}
+
+	d := debounce.NewDebouncer(50*time.Millisecond, 1000)
+
+	// Waiting on an idle debouncer returns right away.
+	d.Wait()
+
+	d.Do(f)
+	d.Wait()
+
+	if c := atomic.LoadUint64(&counter); c != 1 {
+		t.Error("Expected count 1, was", c)
+	}
+
+	// Canceling releases waiters.
+	d.Do(f)
+	go d.Cancel()
+	d.Wait()
+
+	if c := atomic.LoadUint64(&counter); c != 1 {
+		t.Error("Expected count 1, was", c)
+	}
+}
+
+func TestDebounceFlushAndWait(t *testing.T) {
+	var counter uint64
+
+	d := debounce.NewDebouncer(time.Hour, 1000)
+
+	// The debounced function may call back into the debouncer.
+	d.Do(func() {
+		d.Do(func() {
+			atomic.AddUint64(&counter, 1)
+		})
+		atomic.AddUint64(&counter, 1)
+	})
+
+	// The first flush schedules another call, which the second flush runs.
+	d.Flush()
+	d.Flush()
+	d.Wait()
+
+	if c := atomic.LoadUint64(&counter); c != 2 {
+		t.Error("Expected count 2, was", c)
+	}
+}
+
+func TestDebounceFlushDone(t *testing.T) {
+	d := debounce.NewDebouncer(time.Hour, 0)
+
+	// Nothing is pending, so the channel is already closed.
+	select {
+	case <-d.FlushDone():
+	default:
+		t.Fatal("expected a closed channel")
+	}
+
+	release := make(chan struct{})
+	var finished atomic.Bool
+	d.Do(func() {
+		<-release
+		finished.Store(true)
+	})
+
+	done := d.FlushDone()
+	if d.Pending() {
+		t.Error("expected the pending function to be taken")
+	}
+	select {
+	case <-done:
+		t.Fatal("expected the channel to stay open while f is running")
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	close(release)
+	<-done
+	if !finished.Load() {
+		t.Error("expected f to have returned")
+	}
+}
+
+func TestDebounceWaitForNextFire(t *testing.T) {
+	var counter atomic.Int32
+	d := debounce.NewDebouncer(20*time.Millisecond, 0)
+
+	for i := 0; i < 3; i++ {
+		d.Do(func() {
+			counter.Add(1)
+		})
+	}
+	if err := d.WaitForNextFire(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if n := counter.Load(); n != 1 {
+		t.Errorf("expected 1 call, got %d", n)
+	}
+
+	// Nothing is pending, so the context expires first.
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := d.WaitForNextFire(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected %v, got %v", context.DeadlineExceeded, err)
+	}
+}
+
+func TestDebounceFlushAfter(t *testing.T) {
+	var counter uint64
+
+	f := func() {
+		atomic.AddUint64(&counter, 1)
+	}
+
+	d := debounce.NewDebouncer(time.Hour, 1000)
+
+	// Nothing is pending, so there is nothing to guarantee.
+	d.FlushAfter(10 * time.Millisecond)
+
+	d.Do(f)
+	d.FlushAfter(50 * time.Millisecond)
+	d.FlushAfter(time.Hour)
+
+	// The backstop fires even though the debounced function keeps being
+	// called.
+	for i := 0; i < 10; i++ {
+		d.Do(f)
+		time.Sleep(10 * time.Millisecond)
+	}
+	if c := atomic.LoadUint64(&counter); c != 1 {
+		t.Error("Expected count 1, was", c)
+	}
+
+	// Only one of the backstop and the debouncer itself executes f.
+	d.SetAfter(10 * time.Millisecond)
+	d.Do(f)
+	d.FlushAfter(50 * time.Millisecond)
+	time.Sleep(100 * time.Millisecond)
+
+	if c := atomic.LoadUint64(&counter); c != 2 {
+		t.Error("Expected count 2, was", c)
+	}
+	if d.Pending() {
+		t.Error("expected no pending invocation")
+	}
+}
+
+func TestDebounceFlushAfterPaused(t *testing.T) {
+	clock := debounce.NewManualClock(time.Now())
+
+	var counter int
+	f := func() {
+		counter++
+	}
+
+	d := debounce.NewDebouncer(time.Hour, 0, debounce.WithTimerClock(clock))
+
+	// The guarantee doesn't execute f while paused.
+	d.Do(f)
+	d.FlushAfter(10 * time.Second)
+	d.Pause()
+	clock.Advance(time.Minute)
+	if counter != 0 || !d.Pending() {
+		t.Errorf("expected f to stay pending while paused, got %d calls", counter)
+	}
+
+	// It is executed right away once resumed.
+	d.Resume()
+	clock.Advance(0)
+	if counter != 1 {
+		t.Errorf("expected 1 call once resumed, got %d", counter)
+	}
+
+	// A guarantee made while paused starts on Resume for the remainder.
+	d.Do(f)
+	d.Pause()
+	d.FlushAfter(10 * time.Second)
+	clock.Advance(5 * time.Second)
+	d.Resume()
+	clock.Advance(4 * time.Second)
+	if counter != 1 {
+		t.Errorf("expected 1 call before the guarantee, got %d", counter)
+	}
+	clock.Advance(time.Second)
+	if counter != 2 {
+		t.Errorf("expected 2 calls, got %d", counter)
+	}
+}
+
+func TestDebounceWithMaxWait(t *testing.T) {
+	var counter uint64
+
+	f := func() {
+		atomic.AddUint64(&counter, 1)
+	}
+
+	debounced := debounce.NewWithMaxWait(100*time.Millisecond, 150*time.Millisecond, 1000)
+
+	start := time.Now()
+	for time.Since(start) < 140*time.Millisecond {
+		debounced(f)
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	time.Sleep(50 * time.Millisecond)
+
+	// The quiet period never elapsed, but the max wait did.
+	if c := atomic.LoadUint64(&counter); c != 1 {
+		t.Error("Expected count 1, was", c)
+	}
+}
+
+func TestDebounceOnExecute(t *testing.T) {
+	var executions uint64
+
+	d := debounce.NewDebouncer(50*time.Millisecond, 2)
+	d.OnExecute(func() {
+		atomic.AddUint64(&executions, 1)
+
+		// The hook may call back into the debouncer.
+		d.Pending()
+	})
+
+	// The second call reaches the count limit.
+	for i := 0; i < 2; i++ {
+		d.Do(func() {})
+	}
+
+	if n := atomic.LoadUint64(&executions); n != 1 {
+		t.Error("Expected 1 execution, was", n)
+	}
+
+	// The next call starts a new burst, executed once it goes quiet.
+	d.Do(func() {})
+	if n := atomic.LoadUint64(&executions); n != 1 || !d.Pending() {
+		t.Error("Expected a pending execution, was", n)
+	}
+	d.Wait()
+
+	if n := atomic.LoadUint64(&executions); n != 2 {
+		t.Error("Expected 2 executions, was", n)
+	}
+}
+
+func TestDebounceOnCoalesce(t *testing.T) {
+	var coalesced uint64
+
+	d := debounce.NewDebouncer(50*time.Millisecond, 1000)
+	d.OnCoalesce(func() {
+		atomic.AddUint64(&coalesced, 1)
+	})
+
+	// The first call of a burst doesn't coalesce anything.
+	d.Do(func() {})
+
+	if n := atomic.LoadUint64(&coalesced); n != 0 {
+		t.Error("Expected 0 coalesced calls, was", n)
+	}
+
+	for i := 0; i < 4; i++ {
+		d.Do(func() {})
+	}
+	d.Wait()
+
+	if n := atomic.LoadUint64(&coalesced); n != 4 {
+		t.Error("Expected 4 coalesced calls, was", n)
+	}
+}
+
+func TestDebounceOnArm(t *testing.T) {
+	var armed uint64
+
+	d := debounce.NewDebouncer(time.Hour, 1000)
+	d.OnArm(func() {
+		atomic.AddUint64(&armed, 1)
+	})
+
+	// The hook is called once per burst, not on every call.
+	for i := 0; i < 10; i++ {
+		d.Do(func() {})
+	}
+	if c := atomic.LoadUint64(&armed); c != 1 {
+		t.Error("Expected count 1, was", c)
+	}
+
+	d.Flush()
+	d.Do(func() {})
+	if c := atomic.LoadUint64(&armed); c != 2 {
+		t.Error("Expected count 2, was", c)
+	}
+	d.Cancel()
+
+	// A burst started by TryDo calls the hook too.
+	if !d.TryDo(func() {}) {
+		t.Error("Expected TryDo to schedule f")
+	}
+	if c := atomic.LoadUint64(&armed); c != 3 {
+		t.Error("Expected count 3, was", c)
+	}
+	d.Cancel()
+}
+
+func TestDebounceCountOrDuration(t *testing.T) {
+	var counter uint64
+
+	f := func() {
+		atomic.AddUint64(&counter, 1)
+	}
+
+	debounced := debounce.NewCountOrDuration(20*time.Millisecond, 3)
+
+	// Hammer the debouncer so that the count limit and the interval race.
+	for i := 0; i < 30; i++ {
+		debounced(f)
+		debounced(f)
+		debounced(f)
+		time.Sleep(time.Duration(i%3) * 10 * time.Millisecond)
+	}
+	time.Sleep(50 * time.Millisecond)
+
+	// Every third call hits the count limit, and nothing is executed twice.
+	if c := atomic.LoadUint64(&counter); c != 30 {
+		t.Error("Expected count 30, was", c)
+	}
+}
+
+func TestDebouncePauseResume(t *testing.T) {
+	var (
+		counter1 uint64
+		counter2 uint64
+	)
+
+	f1 := func() {
+		atomic.AddUint64(&counter1, 1)
+	}
+
+	f2 := func() {
+		atomic.AddUint64(&counter2, 1)
+	}
+
+	d := debounce.NewDebouncer(50*time.Millisecond, 3)
+
+	d.Do(f1)
+	d.Pause()
+	d.Pause()
+
+	// Neither the quiet period nor the count limit execute while paused.
+	d.Do(f1)
+	d.Do(f1)
+	d.Do(f2)
+	time.Sleep(100 * time.Millisecond)
+
+	if !d.Pending() {
+		t.Error("Expected a pending invocation while paused")
+	}
+	if c := atomic.LoadUint64(&counter2); c != 0 {
+		t.Error("Expected count 0, was", c)
+	}
+
+	d.Resume()
+	d.Resume()
+	d.Wait()
+
+	if c := atomic.LoadUint64(&counter1); c != 0 {
+		t.Error("Expected count 0, was", c)
+	}
+	if c := atomic.LoadUint64(&counter2); c != 1 {
+		t.Error("Expected count 1, was", c)
+	}
+}
+
+func TestDebounceResumeRemaining(t *testing.T) {
+	fired := make(chan time.Time, 1)
+
+	d := debounce.NewDebouncer(100*time.Millisecond, 1000)
+
+	start := time.Now()
+	d.Do(func() {
+		fired <- time.Now()
+	})
+	d.Pause()
+	d.Resume()
+
+	// Resuming doesn't restart the quiet period.
+	if elapsed := (<-fired).Sub(start); elapsed < 100*time.Millisecond || elapsed > 150*time.Millisecond {
+		t.Error("Expected f to fire after 100ms, was", elapsed)
+	}
+}
+
+func TestDebounceCloseReleasesTimers(t *testing.T) {
+	const n = 100000
+
+	var finalized atomic.Int64
+
+	for i := 0; i < n; i++ {
+		// The pending function references the payload.
+		payload := new([16]byte)
+		runtime.SetFinalizer(payload, func(*[16]byte) {
+			finalized.Add(1)
+		})
+
+		d := debounce.NewDebouncer(time.Hour, 1000)
+		d.Do(func() {
+			_ = payload
+		})
+		d.Close()
+	}
+
+	// Without Close, the pending timers would keep every pending function
+	// alive for an hour.
+	deadline := time.Now().Add(5 * time.Second)
+	for finalized.Load() < n/2 && time.Now().Before(deadline) {
+		runtime.GC()
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if c := finalized.Load(); c < n/2 {
+		t.Errorf("Expected closed debouncers to release their pending functions, only %d of %d were", c, n)
+	}
+}
+
+func TestDebounceReleasesPendingFunc(t *testing.T) {
+	clock := debounce.NewManualClock(time.Now())
+
+	for _, tt := range []struct {
+		name    string
+		new     func() debounce.Debouncer
+		release func(d debounce.Debouncer)
+	}{
+		{"Fire", func() debounce.Debouncer {
+			return debounce.NewDebouncer(time.Second, 0, debounce.WithTimerClock(clock))
+		}, func(debounce.Debouncer) {
+			clock.Advance(time.Second)
+		}},
+		{"Flush", func() debounce.Debouncer {
+			return debounce.NewDebouncer(time.Second, 0, debounce.WithTimerClock(clock))
+		}, debounce.Debouncer.Flush},
+		{"Cancel", func() debounce.Debouncer {
+			return debounce.NewDebouncer(time.Second, 0, debounce.WithTimerClock(clock))
+		}, debounce.Debouncer.Cancel},
+		{"Reset", func() debounce.Debouncer {
+			return debounce.NewDebouncer(time.Second, 0, debounce.WithTimerClock(clock))
+		}, func(d debounce.Debouncer) {
+			d.(*debounce.CountDebouncer).Reset()
+		}},
+		{"DurationFire", func() debounce.Debouncer {
+			return debounce.NewDurationDebouncer(time.Second, time.Minute, debounce.WithTimerClock(clock))
+		}, func(debounce.Debouncer) {
+			clock.Advance(time.Second)
+		}},
+		{"DurationCancel", func() debounce.Debouncer {
+			return debounce.NewDurationDebouncer(time.Second, time.Minute, debounce.WithTimerClock(clock))
+		}, debounce.Debouncer.Cancel},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			var finalized atomic.Bool
+
+			d := tt.new()
+			func() {
+				// The pending function references a sizable payload.
+				payload := new([1 << 20]byte)
+				runtime.SetFinalizer(payload, func(*[1 << 20]byte) {
+					finalized.Store(true)
+				})
+				d.Do(func() {
+					_ = payload
+				})
+			}()
+			tt.release(d)
+
+			deadline := time.Now().Add(5 * time.Second)
+			for !finalized.Load() && time.Now().Before(deadline) {
+				runtime.GC()
+				time.Sleep(10 * time.Millisecond)
+			}
+
+			if !finalized.Load() {
+				t.Error("expected the idle debouncer to release its pending function")
+			}
+			runtime.KeepAlive(d)
+		})
+	}
+}
+
+func TestDebounceLastWins(t *testing.T) {
+	var (
+		counter1 uint64
+		counter2 uint64
+	)
+
+	f1 := func() {
+		atomic.AddUint64(&counter1, 1)
+	}
+
+	f2 := func() {
+		atomic.AddUint64(&counter2, 1)
+	}
+
+	d := debounce.NewDebouncer(20*time.Millisecond, 1000)
+
+	// f2 replaces f1 without rearming the timer.
+	d.Do(f1)
+	d.Pause()
+	d.Do(f2)
+	d.Resume()
+	d.Wait()
+
+	if c := atomic.LoadUint64(&counter1); c != 0 {
+		t.Error("Expected count 0, was", c)
+	}
+	if c := atomic.LoadUint64(&counter2); c != 1 {
+		t.Error("Expected count 1, was", c)
+	}
+}
+
+func BenchmarkDebounceWithArg(b *testing.B) {
+	debounced := debounce.NewWithArg[int](time.Hour, math.MaxUint64)
+	f := func(int) {}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		debounced(i, f)
+	}
+}
+
+// BenchmarkDebounceArgClosure compares passing an argument in a closure
+// allocated for every call with NewWithArg. The timer keeps running, so that
+// restarting it doesn't hide the allocation of the closure.
+func BenchmarkDebounceArgClosure(b *testing.B) {
+	f := func(int) {}
+
+	b.Run("Closure", func(b *testing.B) {
+		d := debounce.NewDebouncer(time.Hour, math.MaxUint64, debounce.WithSkipIdentical())
+		defer d.Close()
+
+		b.ReportAllocs()
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			d.Do(func() {
+				f(i)
+			})
+		}
+	})
+
+	b.Run("WithArg", func(b *testing.B) {
+		debounced := debounce.NewWithArg[int](time.Hour, math.MaxUint64, debounce.WithSkipIdentical())
+
+		b.ReportAllocs()
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			debounced(i, f)
+		}
+	})
+}
+
+func TestDebounceStats(t *testing.T) {
+	d := debounce.NewDebouncer(time.Hour, 1000)
+
+	if s := d.Stats(); s != (debounce.Stats{}) {
+		t.Error("Expected zero stats, was", s)
+	}
+
+	for i := 0; i < 3; i++ {
+		d.Do(func() {})
+	}
+
+	s := d.Stats()
+	if s.CurrentCount != 3 || s.Coalesced != 2 || s.Executions != 0 {
+		t.Error("Expected a burst of 3 calls, was", s)
+	}
+
+	before := time.Now()
+	d.Flush()
+
+	s = d.Stats()
+	if s.CurrentCount != 0 || s.Executions != 1 || s.LastExecution.Before(before) {
+		t.Error("Expected a single execution, was", s)
+	}
+}
+
+func TestDebounceBurst(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := debounce.NewManualClock(start)
+
+	d := debounce.NewDebouncer(time.Minute, 0, debounce.WithTimerClock(clock))
+	if burstStart, count, active := d.Burst(); !burstStart.IsZero() || count != 0 || active {
+		t.Errorf("expected an idle burst, got %s, %d, %v", burstStart, count, active)
+	}
+
+	for i := 0; i < 3; i++ {
+		d.Do(func() {})
+		clock.Advance(time.Second)
+	}
+	if burstStart, count, active := d.Burst(); !burstStart.Equal(start) || count != 3 || !active {
+		t.Errorf("expected a burst of 3 calls started at %s, got %s, %d, %v", start, burstStart, count, active)
+	}
+	if count := d.Count(); count != 3 {
+		t.Errorf("expected a count of 3, got %d", count)
+	}
+
+	clock.Advance(time.Minute)
+	if burstStart, count, active := d.Burst(); !burstStart.IsZero() || count != 0 || active {
+		t.Errorf("expected an idle burst once fired, got %s, %d, %v", burstStart, count, active)
+	}
+
+	// The leading call counts too.
+	leading := debounce.NewDebouncer(time.Minute, 0, debounce.WithTimerClock(clock), debounce.WithLeading())
+	for i := 0; i < 5; i++ {
+		leading.Do(func() {})
+	}
+	if _, count, active := leading.Burst(); count != 5 || !active {
+		t.Errorf("expected an active burst of 5 calls, got %d, %v", count, active)
+	}
+	if count := leading.Stats().CurrentCount; count != 5 {
+		t.Errorf("expected a current count of 5, got %d", count)
+	}
+}
+
+func TestDebounceLastFireReason(t *testing.T) {
+	clock := debounce.NewManualClock(time.Now())
+	f := func() {}
+
+	d := debounce.NewDebouncer(time.Minute, 3, debounce.WithTimerClock(clock), debounce.WithMaxWait(90*time.Second))
+	if reason := d.LastFireReason(); reason != debounce.FireNone {
+		t.Errorf("expected %s, got %s", debounce.FireNone, reason)
+	}
+
+	for _, tt := range []struct {
+		reason debounce.FireReason
+		burst  func()
+	}{
+		{debounce.FireTrailing, func() {
+			d.Do(f)
+			clock.Advance(time.Minute)
+		}},
+		{debounce.FireCountLimit, func() {
+			for i := 0; i < 3; i++ {
+				d.Do(f)
+			}
+		}},
+		{debounce.FireMaxWait, func() {
+			d.Do(f)
+			clock.Advance(50 * time.Second)
+			d.Do(f)
+			clock.Advance(40 * time.Second)
+		}},
+		{debounce.FireFlush, func() {
+			d.Do(f)
+			d.Flush()
+		}},
+	} {
+		tt.burst()
+		if reason := d.LastFireReason(); reason != tt.reason {
+			t.Errorf("expected %s, got %s", tt.reason, reason)
+		}
+	}
+
+	leading := debounce.NewDebouncer(time.Minute, 0, debounce.WithTimerClock(clock), debounce.WithLeading())
+	leading.Do(f)
+	if reason := leading.LastFireReason(); reason != debounce.FireLeading {
+		t.Errorf("expected %s, got %s", debounce.FireLeading, reason)
+	}
+}
+
+func TestDebounceLastFired(t *testing.T) {
+	clock := &testClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+
+	count := debounce.NewDebouncer(time.Hour, 1000, debounce.WithClock(clock.Now))
+	duration := debounce.NewDurationDebouncer(time.Hour, 2*time.Hour, debounce.WithClock(clock.Now))
+
+	if !count.LastFired().IsZero() || !duration.LastFired().IsZero() {
+		t.Error("expected a zero time before the first execution")
+	}
+
+	count.Do(func() {})
+	duration.Do(func() {})
+	clock.Add(time.Minute)
+	count.Flush()
+	duration.Flush()
+
+	want := clock.Now()
+	if got := count.LastFired(); !got.Equal(want) {
+		t.Errorf("expected %s, got %s", want, got)
+	}
+	if got := duration.LastFired(); !got.Equal(want) {
+		t.Errorf("expected %s, got %s", want, got)
+	}
+}
+
+func TestDebounceDoFunc(t *testing.T) {
+	d := debounce.NewDebouncer(time.Hour, 3)
+
+	for i, want := range []bool{false, false, true, false} {
+		if got := d.DoFunc(func() {}); got != want {
+			t.Errorf("Expected call %d to return %t, was %t", i+1, want, got)
+		}
+	}
+	d.Cancel()
+}
+
+func TestDebounceReschedule(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := debounce.NewManualClock(start)
+
+	var counter int
+	f := func() {
+		counter++
+	}
+
+	d := debounce.NewDebouncer(time.Minute, 0, debounce.WithTimerClock(clock), debounce.WithMaxWait(5*time.Minute))
+
+	// Without a pending execution, it is a no-op.
+	d.Reschedule(time.Minute)
+	if d.Pending() {
+		t.Error("expected nothing to be pending")
+	}
+
+	d.Do(f)
+	clock.Advance(30 * time.Second)
+	d.Reschedule(time.Minute)
+	if deadline, ok := d.Deadline(); !ok || !deadline.Equal(start.Add(2*time.Minute)) {
+		t.Errorf("expected a deadline in 2m, got %s, %v", deadline, ok)
+	}
+	clock.Advance(time.Minute)
+	if counter != 0 {
+		t.Errorf("expected 0 calls before the rescheduled deadline, got %d", counter)
+	}
+	clock.Advance(30 * time.Second)
+	if counter != 1 {
+		t.Errorf("expected 1 call, got %d", counter)
+	}
+
+	// The max wait still applies.
+	d.Do(f)
+	d.Reschedule(time.Hour)
+	if deadline, ok := d.Deadline(); !ok || !deadline.Equal(start.Add(7*time.Minute)) {
+		t.Errorf("expected the deadline to be capped by the max wait, got %s, %v", deadline, ok)
+	}
+}
+
+func TestDebounceTimeUntilFire(t *testing.T) {
+	clock := &testClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+
+	d := debounce.NewDebouncer(time.Hour, 1000, debounce.WithClock(clock.Now), debounce.WithMaxWait(90*time.Minute))
+	if _, ok := d.TimeUntilFire(); ok {
+		t.Error("expected no scheduled invocation")
+	}
+
+	d.Do(func() {})
+	clock.Add(20 * time.Minute)
+	if remaining, ok := d.TimeUntilFire(); !ok || remaining != 40*time.Minute {
+		t.Errorf("expected 40m remaining, got %s, %v", remaining, ok)
+	}
+
+	// Rearming restarts the quiet period, up to the max wait.
+	clock.Add(40 * time.Minute)
+	d.Do(func() {})
+	if remaining, ok := d.TimeUntilFire(); !ok || remaining != 30*time.Minute {
+		t.Errorf("expected 30m remaining, got %s, %v", remaining, ok)
+	}
+
+	d.Cancel()
+	if _, ok := d.TimeUntilFire(); ok {
+		t.Error("expected no scheduled invocation after cancel")
+	}
+}
+
+func TestDebounceDeadline(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := debounce.NewManualClock(start)
+
+	d := debounce.NewDebouncer(time.Minute, 0, debounce.WithTimerClock(clock))
+	if _, ok := d.Deadline(); ok {
+		t.Error("expected no deadline")
+	}
+
+	d.Do(func() {})
+	if deadline, ok := d.Deadline(); !ok || !deadline.Equal(start.Add(time.Minute)) {
+		t.Errorf("expected a deadline in 1m, got %s, %v", deadline, ok)
+	}
+
+	// Every call reschedules the deadline.
+	clock.Advance(30 * time.Second)
+	d.Do(func() {})
+	if deadline, ok := d.Deadline(); !ok || !deadline.Equal(start.Add(90*time.Second)) {
+		t.Errorf("expected a deadline in 1m30s, got %s, %v", deadline, ok)
+	}
+
+	clock.Advance(time.Minute)
+	if _, ok := d.Deadline(); ok {
+		t.Error("expected no deadline once fired")
+	}
+}
+
+func TestDebounceSetAfter(t *testing.T) {
+	fired := make(chan time.Time, 1)
+
+	d := debounce.NewDebouncer(time.Hour, 1000)
+	d.SetAfter(20 * time.Millisecond)
+
+	start := time.Now()
+	d.Do(func() {
+		fired <- time.Now()
+	})
+
+	select {
+	case <-fired:
+	case <-time.After(time.Second):
+		t.Fatal("Expected the new quiet period to apply, waited", time.Since(start))
+	}
+}
+
+func BenchmarkDebounceTimers(b *testing.B) {
+	for _, bb := range []struct {
+		name string
+		opts []debounce.Option
+	}{
+		{"AfterFunc", nil},
+		{"ReusableTimer", []debounce.Option{debounce.WithReusableTimer()}},
+	} {
+		b.Run(bb.name, func(b *testing.B) {
+			d := debounce.NewDebouncer(time.Millisecond, 0, bb.opts...)
+			defer d.Close()
+			f := func() {}
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				d.Do(f)
+			}
+		})
+	}
+}
+
+func BenchmarkDebouncePolling(b *testing.B) {
+	for _, bb := range []struct {
+		name string
+		poll func(d *debounce.CountDebouncer)
+	}{
+		{"Count", func(d *debounce.CountDebouncer) {
+			_ = d.Count()
+			_ = d.Pending()
+		}},
+		{"Stats", func(d *debounce.CountDebouncer) {
+			_ = d.Stats().CurrentCount
+		}},
+	} {
+		b.Run(bb.name, func(b *testing.B) {
+			d := debounce.NewDebouncer(time.Hour, 0)
+			defer d.Close()
+
+			// Keep scheduling while the pollers read.
+			stop := make(chan struct{})
+			var wg sync.WaitGroup
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				f := func() {}
+				for {
+					select {
+					case <-stop:
+						return
+					default:
+						d.Do(f)
+					}
+				}
+			}()
+
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					bb.poll(d)
+				}
+			})
+			b.StopTimer()
+
+			close(stop)
+			wg.Wait()
+		})
+	}
+}
@@ -6,70 +6,527 @@
 package debounce
 
 import (
+	"math"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
+// now is the default clock of new debouncers. Instances only read it when
+// they are created and use their own clock afterwards, see WithClock, so tests
+// should inject a clock per debouncer rather than replace now.
 var now = time.Now
 
 // NewDebounceByDuration returns a debounced function that takes another function as its argument.
 // This function will be called at the given interval, but no more than the max duration
 // from the first call.
-func NewDebounceByDuration(interval, maxDuration time.Duration) func(f func()) {
-	d := &durationDebouncer{
+//
+// Two independent timers are involved. The quiet period timer is restarted by
+// every call and fires once the debounced function hasn't been called for the
+// given interval, so after the last call of a burst, f is executed exactly
+// once within the interval. The max duration timer is started by the first
+// call of a burst and is never restarted, so a stream of calls faster than the
+// interval still executes f every max duration. Whichever timer fires first
+// executes f and ends the burst, stopping the other one.
+//
+// The quiet period never extends past the max duration, so an interval longer
+// than the max duration behaves like one equal to it: f is executed the max
+// duration after the first call of a burst. A max duration of zero means no
+// max duration, like WithMaxWait.
+func NewDebounceByDuration(interval, maxDuration time.Duration, opts ...Option) func(f func()) {
+	return NewDurationDebouncer(interval, maxDuration, opts...).Do
+}
+
+// NewDebounceByDurationAndCount is like NewDebounceByDuration, but f is also
+// executed right away once the debounced function has been called countLimit
+// times within a burst, like with New. A countLimit of zero means
+// no limit.
+func NewDebounceByDurationAndCount(interval, maxDuration time.Duration, countLimit uint64, opts ...Option) func(f func()) {
+	d := NewDurationDebouncer(interval, maxDuration, opts...)
+	d.countLimit = noLimit(countLimit)
+	return d.Do
+}
+
+// NewDurationDebouncer returns a DurationDebouncer with the same semantics as
+// NewDebounceByDuration. Unlike the bare function, it can also be canceled or
+// flushed.
+func NewDurationDebouncer(interval, maxDuration time.Duration, opts ...Option) *DurationDebouncer {
+	c := newConfig(opts)
+
+	d := &DurationDebouncer{
+		name:        c.name,
+		registry:    c.registry,
+		now:         c.now,
+		afterFunc:   c.afterFunc,
 		interval:    interval,
 		maxDuration: maxDuration,
+		countLimit:  math.MaxUint64,
 	}
-
-	return func(f func()) {
-		d.add(f)
+	d.idle = sync.NewCond(&d.mu)
+	d.closedCh = make(chan struct{})
+	if d.registry != nil {
+		d.registry.add(d)
 	}
+
+	return d
 }
 
-type durationDebouncer struct {
+// DurationDebouncer is a debouncer bounded by a maximum duration.
+// See NewDebounceByDuration for the semantics of Do.
+type DurationDebouncer struct {
 	mu          sync.Mutex
+	name        string
+	registry    *Registry
+	now         func() time.Time
+	afterFunc   func(time.Duration, func()) Timer
 	interval    time.Duration
 	maxDuration time.Duration
-	timer       *time.Timer
+	countLimit  uint64
+	count       uint64
+	timer       Timer
+	maxTimer    Timer
+	gen         uint64 // Incremented whenever the timer is armed or reset.
+	burst       uint64 // Incremented whenever the max timer is reset.
 	firstCall   bool
+	uncapped    bool // Set by CancelMaxWait for the current burst.
 	startTime   time.Time
+	pending     func() // The last function passed to Do.
+	closed      bool
+	closedCh    chan struct{} // Closed once closed is set.
+	lastFired   time.Time
+	lastReason  FireReason
+	onPanic     func(any)
+	onExecute   func()
+	onCoalesce  func()
+
+	// busy is the number of bursts in progress and running functions, idle
+	// is signaled when it drops to zero.
+	busy int
+	idle *sync.Cond
+
+	executed  atomic.Uint64
+	coalesced atomic.Uint64
 }
 
-func (d *durationDebouncer) add(f func()) {
+// Do schedules f to be called after the interval, but no more than the max
+// duration from the first call.
+// f is always executed without holding the debouncer's lock.
+// f may be nil to start or extend the burst before the function to execute is
+// known, see CountDebouncer.Do.
+func (d *DurationDebouncer) Do(f func()) {
+	run, onCoalesce := d.add(f)
+	if onCoalesce != nil {
+		onCoalesce()
+	}
+	if run != nil {
+		d.execute(run)
+	}
+}
+
+// add registers f and returns the function to execute right away, if any,
+// and the hook to call if a call was coalesced.
+func (d *DurationDebouncer) add(f func()) (run, onCoalesce func()) {
 	d.mu.Lock()
 	defer d.mu.Unlock()
 
-	now := now()
+	return d.addLocked(f)
+}
+
+// addLocked is add for callers holding d.mu.
+func (d *DurationDebouncer) addLocked(f func()) (run, onCoalesce func()) {
+	if d.closed {
+		return nil, nil
+	}
+
+	now := d.now()
 	if !d.firstCall {
 		d.firstCall = true
 		d.startTime = now
+		d.busy++
+		// Make sure f is executed once the max duration has passed, even if
+		// the calls keep coming faster than the interval.
+		if d.maxDuration > 0 {
+			d.maxTimer = d.afterMax(d.maxDuration)
+		}
 	}
 
+	if f != nil {
+		d.pending = f
+	}
+	d.count++
+	expired := false
 	if d.timer != nil {
-		d.timer.Stop()
+		expired = !d.timer.Stop()
+		d.coalesced.Add(1)
+		onCoalesce = d.onCoalesce
 	}
 
-	remainingDuration := d.maxDuration - time.Since(d.startTime)
-	if remainingDuration <= 0 {
+	after, reason := d.interval, FireTrailing
+	if !d.uncapped && d.maxDuration > 0 {
+		remainingDuration := d.maxDuration - now.Sub(d.startTime)
+		if remainingDuration <= 0 {
+			return d.take(FireMaxWait), onCoalesce
+		}
+		// Never let the quiet period overshoot the max duration.
+		if remainingDuration < after {
+			after, reason = remainingDuration, FireMaxWait
+		}
+	}
+	if d.count >= d.countLimit {
+		return d.take(FireCountLimit), onCoalesce
+	}
+	if expired {
+		// The interval elapsed just now and the timer's callback, still
+		// current, is about to execute the pending function, which is f.
+		return nil, onCoalesce
+	}
+
+	d.gen++
+	gen := d.gen
+	d.timer = d.afterFunc(after, func() {
+		d.fire(reason, func() bool { return gen == d.gen })
+	})
+
+	return nil, onCoalesce
+}
+
+// afterMax arms the max duration timer of the current burst.
+func (d *DurationDebouncer) afterMax(after time.Duration) Timer {
+	burst := d.burst
+	return d.afterFunc(after, func() {
+		d.fire(FireMaxWait, func() bool { return burst == d.burst })
+	})
+}
+
+// fire is called when either timer expires and executes the pending function
+// for the given reason. current is called with d.mu held and reports whether
+// the timer is still current; if it has been rearmed or reset since, it was
+// stopped too late and fire is a no-op.
+func (d *DurationDebouncer) fire(reason FireReason, current func() bool) {
+	d.mu.Lock()
+	if !current() {
+		d.mu.Unlock()
+		return
+	}
+	f := d.take(reason)
+	d.mu.Unlock()
+
+	if f != nil {
+		d.execute(f)
+	}
+}
+
+// Cancel drops the pending invocation, if any, without executing it, stopping
+// both the quiet period and the max duration timers. See CancelMaxWait and
+// CancelTrailing to stop just one of them. The debouncer can be used again
+// afterwards.
+func (d *DurationDebouncer) Cancel() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.reset()
+}
+
+// CancelMaxWait lifts the max duration for the burst in progress, if any,
+// e.g. during a batch that is known to be large, leaving f to be executed
+// once the debounced function stops being called for the interval. The max
+// duration applies again from the next burst on. If the quiet period was
+// stopped by CancelTrailing, nothing would execute f, so CancelMaxWait drops
+// it and ends the burst like Cancel.
+func (d *DurationDebouncer) CancelMaxWait() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if !d.firstCall {
+		return
+	}
+	if d.timer == nil {
 		d.reset()
-		f()
 		return
 	}
+	d.uncapped = true
+	d.burst++
+	if d.maxTimer != nil {
+		d.maxTimer.Stop()
+		d.maxTimer = nil
+	}
+}
 
-	d.timer = time.AfterFunc(d.interval, func() {
-		d.mu.Lock()
-		defer d.mu.Unlock()
+// CancelTrailing stops the quiet period timer without dropping the pending
+// function, leaving it to be executed once the max duration has passed. The
+// next call restarts the quiet period as usual. Without a max duration, or if
+// it was lifted by CancelMaxWait, nothing would execute f, so CancelTrailing
+// drops it and ends the burst like Cancel.
+func (d *DurationDebouncer) CancelTrailing() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
 
-		f()
+	if d.maxTimer == nil {
 		d.reset()
-	})
+		return
+	}
+	d.gen++
+	if d.timer != nil {
+		d.timer.Stop()
+		d.timer = nil
+	}
+}
+
+// Reset clears the state of the current burst, dropping the pending
+// invocation, if any, without executing it. It is equivalent to Cancel and
+// is provided for callers reusing a debouncer across separate batches.
+func (d *DurationDebouncer) Reset() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.reset()
+}
+
+// Flush stops the timer and executes the pending function, if any, right away.
+func (d *DurationDebouncer) Flush() {
+	d.mu.Lock()
+	f := d.take(FireFlush)
+	d.mu.Unlock()
+
+	if f != nil {
+		d.execute(f)
+	}
+}
+
+// Pending reports whether an invocation is scheduled but has not fired yet.
+func (d *DurationDebouncer) Pending() bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return d.timer != nil || d.maxTimer != nil
+}
+
+// Name returns the name of the debouncer set by WithName, if any.
+func (d *DurationDebouncer) Name() string {
+	return d.name
+}
+
+// OnPanic registers a handler which is called with the recovered value if a
+// debounced function panics. Without a handler, the panic is propagated.
+// Either way, the debouncer remains usable.
+func (d *DurationDebouncer) OnPanic(handler func(any)) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.onPanic = handler
 }
 
-func (d *durationDebouncer) reset() {
+// OnExecute registers a hook which is called every time a debounced function
+// has been executed. The hook is called without holding the debouncer's lock.
+func (d *DurationDebouncer) OnExecute(hook func()) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.onExecute = hook
+}
+
+// OnCoalesce registers a hook which is called every time a call is coalesced
+// with another one, i.e. when a pending call is superseded by a later call.
+// It is not called for the first call of a burst. The hook is called without
+// holding the debouncer's lock.
+func (d *DurationDebouncer) OnCoalesce(hook func()) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.onCoalesce = hook
+}
+
+// Interval returns the quiet period after which f is executed.
+func (d *DurationDebouncer) Interval() time.Duration {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return d.interval
+}
+
+// MaxDuration returns the max duration from the first call of a burst after
+// which f is executed, even if the debounced function keeps being called.
+func (d *DurationDebouncer) MaxDuration() time.Duration {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return d.maxDuration
+}
+
+// SetInterval changes the interval. The new interval applies from the next
+// call to Do on; a timer that is already running isn't rearmed.
+func (d *DurationDebouncer) SetInterval(interval time.Duration) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.interval = interval
+}
+
+// SetMaxDuration changes the max duration, including for the burst in
+// progress, if any. A max duration of zero means no max duration.
+func (d *DurationDebouncer) SetMaxDuration(maxDuration time.Duration) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.maxDuration = maxDuration
+	d.burst++
+	if d.maxTimer != nil {
+		d.maxTimer.Stop()
+		d.maxTimer = nil
+	}
+	if d.firstCall && !d.uncapped && d.maxDuration > 0 {
+		remaining := max(d.maxDuration-d.now().Sub(d.startTime), 0)
+		d.maxTimer = d.afterMax(remaining)
+	}
+}
+
+// Close drops the pending invocation, if any, releases the timers and removes
+// the debouncer from its registry, if any.
+// Subsequent calls to Do are no-ops. Close may be called more than once.
+func (d *DurationDebouncer) Close() {
+	d.mu.Lock()
+	d.closed = true
+	d.reset()
+	d.closeDone()
+	d.mu.Unlock()
+
+	if d.registry != nil {
+		d.registry.remove(d)
+	}
+}
+
+// LastFired returns the time a function was last executed, according to the
+// debouncer's clock, or the zero time if none has been executed yet.
+func (d *DurationDebouncer) LastFired() time.Time {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return d.lastFired
+}
+
+// LastFireReason returns why the last function was executed, or FireNone if
+// none has been executed yet.
+func (d *DurationDebouncer) LastFireReason() FireReason {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return d.lastReason
+}
+
+// Executed returns the number of times a function has been executed.
+func (d *DurationDebouncer) Executed() uint64 {
+	return d.executed.Load()
+}
+
+// Coalesced returns the number of calls that were superseded by a later call
+// and therefore never executed on their own.
+func (d *DurationDebouncer) Coalesced() uint64 {
+	return d.coalesced.Load()
+}
+
+// CloseFlush is like Close, but executes the pending function, if any, right
+// away before returning, so that the last call isn't lost on shutdown.
+func (d *DurationDebouncer) CloseFlush() {
+	d.mu.Lock()
+	f := d.take(FireFlush)
+	d.closed = true
+	d.closeDone()
+	d.mu.Unlock()
+
+	if d.registry != nil {
+		d.registry.remove(d)
+	}
+	if f != nil {
+		d.execute(f)
+	}
+}
+
+// Done returns a channel which is closed once the debouncer is closed with
+// Close or CloseFlush, like the Done channel of a context.Context, e.g. so that
+// goroutines feeding the debouncer can return.
+func (d *DurationDebouncer) Done() <-chan struct{} {
+	return d.closedCh
+}
+
+// closeDone closes d.closedCh unless it is already closed.
+func (d *DurationDebouncer) closeDone() {
+	select {
+	case <-d.closedCh:
+	default:
+		close(d.closedCh)
+	}
+}
+
+// Wait blocks until the pending invocation, if any, has been executed and
+// any function that is currently being executed has returned. Combined with
+// Flush, it executes the pending function right away and waits for it.
+// Wait must not be called from a debounced function.
+func (d *DurationDebouncer) Wait() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for d.busy > 0 {
+		d.idle.Wait()
+	}
+}
+
+// take resets the debouncer and returns the pending function, if any, which
+// must then be passed to execute. reason is recorded as the reason for the
+// execution.
+func (d *DurationDebouncer) take(reason FireReason) func() {
+	f := d.pending
+	d.reset()
+	if f != nil {
+		d.executed.Add(1)
+		d.lastReason = reason
+		d.lastFired = d.now()
+		d.busy++
+	}
+	return f
+}
+
+// execute runs f, which must be called without holding d.mu.
+func (d *DurationDebouncer) execute(f func()) {
+	d.mu.Lock()
+	onPanic, onExecute := d.onPanic, d.onExecute
+	d.mu.Unlock()
+
+	defer func() {
+		d.mu.Lock()
+		d.done()
+		d.mu.Unlock()
+	}()
+
+	call(f, onPanic)
+	if onExecute != nil {
+		onExecute()
+	}
+}
+
+func (d *DurationDebouncer) done() {
+	d.busy--
+	if d.busy == 0 {
+		d.idle.Broadcast()
+	}
+}
+
+func (d *DurationDebouncer) reset() {
+	d.gen++
+	d.burst++
+	if d.firstCall {
+		d.done()
+	}
 	d.firstCall = false
+	d.uncapped = false
 	if d.timer != nil {
 		d.timer.Stop()
 		d.timer = nil
 	}
+	if d.maxTimer != nil {
+		d.maxTimer.Stop()
+		d.maxTimer = nil
+	}
 	d.startTime = time.Time{}
+	d.count = 0
+	d.pending = nil
 }
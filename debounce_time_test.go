@@ -1,34 +1,11 @@
 package debounce
 
 import (
-	"sync"
+	"slices"
 	"testing"
 	"time"
 )
 
-var (
-	mockNowFunc  func() time.Time
-	mockNowMutex sync.Mutex
-)
-
-func mockNow() time.Time {
-	mockNowMutex.Lock()
-	defer mockNowMutex.Unlock()
-	return mockNowFunc()
-}
-
-func setMockNow(t time.Time) {
-	mockNowMutex.Lock()
-	defer mockNowMutex.Unlock()
-	mockNowFunc = func() time.Time {
-		return t
-	}
-}
-
-func init() {
-	now = mockNow
-}
-
 func TestTimeDebounce(t *testing.T) {
 	tests := []struct {
 		name        string
@@ -60,33 +37,646 @@ func TestTimeDebounce(t *testing.T) {
 			maxDuration: 300 * time.Millisecond,
 			timeSteps:   []time.Duration{50 * time.Millisecond, 50 * time.Millisecond, 200 * time.Millisecond},
 		},
+		{
+			name:        "Single call after interval",
+			interval:    100 * time.Millisecond,
+			maxDuration: 500 * time.Millisecond,
+			timeSteps:   []time.Duration{150 * time.Millisecond, 50 * time.Millisecond},
+		},
+		{
+			name:        "Calls until max duration",
+			interval:    100 * time.Millisecond,
+			maxDuration: 200 * time.Millisecond,
+			timeSteps:   []time.Duration{60 * time.Millisecond, 60 * time.Millisecond, 60 * time.Millisecond, 60 * time.Millisecond},
+		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			callCount := 0
-			mu := sync.Mutex{}
 			f := func() {
-				mu.Lock()
-				defer mu.Unlock()
 				callCount++
 			}
 
-			d := NewDebounceByDuration(tt.interval, tt.maxDuration)
-			start := time.Now()
-			setMockNow(start)
+			clock := NewManualClock(time.Now())
+			d := NewDebounceByDuration(tt.interval, tt.maxDuration, WithTimerClock(clock))
 
-			for _, step := range tt.timeSteps {
-				setMockNow(start.Add(step))
+			for i, step := range tt.timeSteps {
 				d(f)
-				time.Sleep(step)
+				if i == len(tt.timeSteps)-1 {
+					// Check just before the last step ends, so that a
+					// timer expiring right then doesn't count yet.
+					step -= time.Nanosecond
+				}
+				clock.Advance(step)
 			}
 
-			mu.Lock()
 			if callCount != 1 {
 				t.Errorf("expected 1 calls, got %d", callCount)
 			}
-			mu.Unlock()
 		})
 	}
 }
+
+func TestTimeDebounceCancel(t *testing.T) {
+	callCount := 0
+	f := func() {
+		callCount++
+	}
+
+	clock := NewManualClock(time.Now())
+	d := NewDurationDebouncer(50*time.Millisecond, 500*time.Millisecond, WithTimerClock(clock))
+	d.Cancel()
+
+	d.Do(f)
+	d.Do(f)
+	d.Cancel()
+
+	clock.Advance(time.Second)
+	if callCount != 0 {
+		t.Errorf("expected 0 calls, got %d", callCount)
+	}
+
+	d.Do(f)
+	clock.Advance(100 * time.Millisecond)
+	if callCount != 1 {
+		t.Errorf("expected 1 call, got %d", callCount)
+	}
+}
+
+func TestTimeDebounceFlush(t *testing.T) {
+	callCount := 0
+	f := func() {
+		callCount++
+	}
+
+	d := NewDurationDebouncer(time.Hour, 2*time.Hour)
+	d.Flush()
+
+	d.Do(f)
+	d.Do(f)
+	d.Flush()
+	d.Flush()
+
+	if callCount != 1 {
+		t.Errorf("expected 1 call, got %d", callCount)
+	}
+}
+
+func TestTimeDebouncePending(t *testing.T) {
+	d := NewDurationDebouncer(50*time.Millisecond, 500*time.Millisecond)
+	if d.Pending() {
+		t.Error("expected no pending invocation")
+	}
+
+	d.Do(func() {})
+	if !d.Pending() {
+		t.Error("expected a pending invocation")
+	}
+
+	d.Cancel()
+	if d.Pending() {
+		t.Error("expected no pending invocation after cancel")
+	}
+}
+
+func TestTimeDebounceCounters(t *testing.T) {
+	d := NewDurationDebouncer(time.Hour, 2*time.Hour)
+	for i := 0; i < 3; i++ {
+		d.Do(func() {})
+	}
+	d.Flush()
+
+	if n := d.Executed(); n != 1 {
+		t.Errorf("expected 1 execution, got %d", n)
+	}
+	if n := d.Coalesced(); n != 2 {
+		t.Errorf("expected 2 coalesced calls, got %d", n)
+	}
+}
+
+func TestTimeDebounceMaxDurationContinuousCalls(t *testing.T) {
+	callCount := 0
+	f := func() {
+		callCount++
+	}
+
+	// Calls arrive faster than the interval, so only the max duration can fire.
+	clock := NewManualClock(time.Now())
+	d := NewDurationDebouncer(100*time.Millisecond, 200*time.Millisecond, WithTimerClock(clock))
+	for i := 0; i < 5; i++ {
+		d.Do(f)
+		clock.Advance(50 * time.Millisecond)
+	}
+
+	if callCount != 1 {
+		t.Errorf("expected 1 call, got %d", callCount)
+	}
+	d.Cancel()
+}
+
+func TestTimeDebounceReset(t *testing.T) {
+	clock := NewManualClock(time.Now())
+
+	callCount := 0
+	f := func() {
+		callCount++
+	}
+
+	d := NewDurationDebouncer(time.Hour, time.Minute, WithTimerClock(clock))
+	d.Do(f)
+	d.Reset()
+
+	// The burst restarts, so the max duration hasn't passed yet.
+	clock.Advance(time.Minute)
+	d.Do(f)
+
+	if callCount != 0 {
+		t.Errorf("expected 0 calls, got %d", callCount)
+	}
+	d.Reset()
+}
+
+func TestTimeDebounceClose(t *testing.T) {
+	clock := NewManualClock(time.Now())
+	d := NewDurationDebouncer(50*time.Millisecond, 100*time.Millisecond, WithTimerClock(clock))
+	d.Do(func() {})
+	d.Close()
+	d.Close()
+
+	d.Do(func() {
+		t.Error("expected no calls after close")
+	})
+	if d.Pending() {
+		t.Error("expected no pending invocation")
+	}
+	clock.Advance(time.Second)
+}
+
+func TestTimeDebounceDone(t *testing.T) {
+	d := NewDurationDebouncer(time.Hour, 2*time.Hour)
+
+	select {
+	case <-d.Done():
+		t.Fatal("expected Done to be open before Close")
+	default:
+	}
+
+	d.Close()
+	d.Close()
+	select {
+	case <-d.Done():
+	default:
+		t.Error("expected Done to be closed by Close")
+	}
+
+	flushed := NewDurationDebouncer(time.Hour, 2*time.Hour)
+	flushed.CloseFlush()
+	select {
+	case <-flushed.Done():
+	default:
+		t.Error("expected Done to be closed by CloseFlush")
+	}
+}
+
+func TestTimeDebounceWait(t *testing.T) {
+	callCount := 0
+	f := func() {
+		callCount++
+	}
+
+	d := NewDurationDebouncer(50*time.Millisecond, 100*time.Millisecond)
+	d.Wait()
+
+	d.Do(f)
+	d.Wait()
+
+	if callCount != 1 {
+		t.Errorf("expected 1 call, got %d", callCount)
+	}
+}
+
+func TestTimeDebounceSetMaxDuration(t *testing.T) {
+	fired := make(chan struct{}, 1)
+
+	d := NewDurationDebouncer(time.Hour, 2*time.Hour)
+	d.Do(func() {
+		fired <- struct{}{}
+	})
+
+	// Shortening the max duration applies to the burst in progress.
+	d.SetMaxDuration(20 * time.Millisecond)
+
+	select {
+	case <-fired:
+	case <-time.After(time.Second):
+		t.Fatal("expected the new max duration to apply")
+	}
+
+	d.SetInterval(10 * time.Millisecond)
+	d.SetMaxDuration(time.Hour)
+	d.Do(func() {
+		fired <- struct{}{}
+	})
+
+	select {
+	case <-fired:
+	case <-time.After(time.Second):
+		t.Fatal("expected the new interval to apply")
+	}
+}
+
+// lateClock is a Clock whose timers only fire when the test runs their
+// callbacks, and always report that they already fired when stopped, as if
+// the callback was blocked on the debouncer's lock.
+type lateClock struct {
+	callbacks []func()
+}
+
+func (c *lateClock) Now() time.Time {
+	return time.Now()
+}
+
+func (c *lateClock) AfterFunc(d time.Duration, f func()) Timer {
+	c.callbacks = append(c.callbacks, f)
+	return lateTimer{}
+}
+
+type lateTimer struct{}
+
+func (lateTimer) Stop() bool {
+	return false
+}
+
+func TestStaleTimerCallback(t *testing.T) {
+	t.Run("count", func(t *testing.T) {
+		var clock lateClock
+		calls := 0
+
+		d := NewDebouncer(time.Second, 1000, WithTimerClock(&clock))
+		d.Do(func() {})
+		d.Cancel()
+		d.Do(func() {
+			calls++
+		})
+
+		clock.callbacks[0]()
+		if calls != 0 {
+			t.Error("expected the stale callback not to execute the new call")
+		}
+
+		clock.callbacks[1]()
+		if calls != 1 {
+			t.Errorf("expected the current callback to execute the new call, got %d calls", calls)
+		}
+	})
+
+	t.Run("duration cancel", func(t *testing.T) {
+		var clock lateClock
+		calls := 0
+
+		d := NewDurationDebouncer(time.Second, 2*time.Second, WithTimerClock(&clock))
+		d.Do(func() {})
+		d.Cancel()
+		d.Do(func() {
+			calls++
+		})
+
+		// Both the interval and the max duration timers of the canceled
+		// burst are stale.
+		clock.callbacks[0]()
+		clock.callbacks[1]()
+		if calls != 0 {
+			t.Error("expected the stale callbacks not to execute the new call")
+		}
+
+		clock.callbacks[2]()
+		if calls != 1 {
+			t.Errorf("expected the current callback to execute the new call, got %d calls", calls)
+		}
+	})
+
+	t.Run("duration flush", func(t *testing.T) {
+		var clock lateClock
+		calls := 0
+
+		d := NewDurationDebouncer(time.Second, 2*time.Second, WithTimerClock(&clock))
+		d.Do(func() {})
+		d.Flush()
+		d.Do(func() {
+			calls++
+		})
+
+		clock.callbacks[0]()
+		clock.callbacks[1]()
+		if calls != 0 {
+			t.Error("expected the stale callbacks not to execute the new call")
+		}
+	})
+
+	t.Run("duration call at expiry", func(t *testing.T) {
+		var clock lateClock
+		calls := 0
+
+		// A call made as the interval elapses is executed by the expiring
+		// timer, once.
+		d := NewDurationDebouncer(time.Second, 2*time.Second, WithTimerClock(&clock))
+		d.Do(func() {})
+		d.Do(func() {
+			calls++
+		})
+		if n := len(clock.callbacks); n != 2 {
+			t.Fatalf("expected the expired timer not to be rearmed, got %d timers", n)
+		}
+
+		for _, f := range clock.callbacks {
+			f()
+		}
+		if calls != 1 {
+			t.Errorf("expected 1 call, got %d", calls)
+		}
+	})
+}
+
+func TestTimeDebounceContinuousStream(t *testing.T) {
+	var fires []time.Duration
+
+	clock := NewManualClock(time.Now())
+	start := clock.Now()
+	d := NewDurationDebouncer(50*time.Millisecond, 100*time.Millisecond, WithTimerClock(clock))
+
+	if d.Interval() != 50*time.Millisecond || d.MaxDuration() != 100*time.Millisecond {
+		t.Fatalf("unexpected interval %s and max duration %s", d.Interval(), d.MaxDuration())
+	}
+
+	// A stream of calls faster than the interval, exceeding the max duration
+	// three times over.
+	for i := 0; i < 35; i++ {
+		d.Do(func() {
+			fires = append(fires, clock.Now().Sub(start))
+		})
+		clock.Advance(10 * time.Millisecond)
+	}
+	clock.Advance(time.Second)
+
+	// The max duration fires during the stream, and the quiet period once
+	// after it.
+	want := []time.Duration{100 * time.Millisecond, 200 * time.Millisecond, 300 * time.Millisecond, 390 * time.Millisecond}
+	if !slices.Equal(fires, want) {
+		t.Errorf("expected calls at %v, got %v", want, fires)
+	}
+}
+
+func TestTimeDebounceClampedInterval(t *testing.T) {
+	var fires []time.Duration
+
+	clock := NewManualClock(time.Now())
+	start := clock.Now()
+	d := NewDurationDebouncer(150*time.Millisecond, 300*time.Millisecond, WithTimerClock(clock))
+
+	// The last call leaves 100ms until the max duration, less than the
+	// interval, so f fires at the cap rather than 150ms after it.
+	for i := 0; i < 3; i++ {
+		d.Do(func() {
+			fires = append(fires, clock.Now().Sub(start))
+		})
+		clock.Advance(100 * time.Millisecond)
+	}
+	clock.Advance(time.Second)
+
+	if want := []time.Duration{300 * time.Millisecond}; !slices.Equal(fires, want) {
+		t.Errorf("expected calls at %v, got %v", want, fires)
+	}
+}
+
+func TestTimeDebounceCountLimit(t *testing.T) {
+	callCount := 0
+	f := func() {
+		callCount++
+	}
+
+	clock := NewManualClock(time.Now())
+	debounced := NewDebounceByDurationAndCount(time.Minute, time.Hour, 3, WithTimerClock(clock))
+
+	for i := 0; i < 3; i++ {
+		debounced(f)
+	}
+	if callCount != 1 {
+		t.Errorf("expected 1 call, got %d", callCount)
+	}
+
+	// The count is reset along with the burst.
+	for i := 0; i < 2; i++ {
+		debounced(f)
+	}
+	if callCount != 1 {
+		t.Errorf("expected 1 call, got %d", callCount)
+	}
+	clock.Advance(time.Minute)
+	if callCount != 2 {
+		t.Errorf("expected 2 calls, got %d", callCount)
+	}
+}
+
+func TestTimeDebounceCancelMaxWait(t *testing.T) {
+	callCount := 0
+	f := func() {
+		callCount++
+	}
+
+	clock := NewManualClock(time.Now())
+	d := NewDurationDebouncer(time.Minute, 2*time.Minute, WithTimerClock(clock))
+
+	// Without the cap, calls faster than the interval keep pushing f out.
+	d.Do(f)
+	d.CancelMaxWait()
+	for i := 0; i < 5; i++ {
+		clock.Advance(30 * time.Second)
+		d.Do(f)
+	}
+	if callCount != 0 {
+		t.Errorf("expected 0 calls past the max duration, got %d", callCount)
+	}
+	clock.Advance(time.Minute)
+	if callCount != 1 {
+		t.Errorf("expected 1 call after the interval, got %d", callCount)
+	}
+
+	// The cap applies again to the next burst.
+	for i := 0; i < 5; i++ {
+		d.Do(f)
+		clock.Advance(30 * time.Second)
+	}
+	if callCount != 2 {
+		t.Errorf("expected 2 calls, got %d", callCount)
+	}
+}
+
+func TestTimeDebounceCancelTrailing(t *testing.T) {
+	callCount := 0
+	f := func() {
+		callCount++
+	}
+
+	clock := NewManualClock(time.Now())
+	d := NewDurationDebouncer(time.Minute, 5*time.Minute, WithTimerClock(clock))
+
+	// f is left to the max duration timer.
+	d.Do(f)
+	d.CancelTrailing()
+	if !d.Pending() {
+		t.Error("expected f to still be pending")
+	}
+	clock.Advance(4 * time.Minute)
+	if callCount != 0 {
+		t.Errorf("expected 0 calls before the max duration, got %d", callCount)
+	}
+	clock.Advance(time.Minute)
+	if callCount != 1 || d.Pending() {
+		t.Errorf("expected 1 call at the max duration, got %d", callCount)
+	}
+
+	// The next call restarts the quiet period as usual.
+	d.Do(f)
+	d.CancelTrailing()
+	d.Do(f)
+	clock.Advance(time.Minute)
+	if callCount != 2 {
+		t.Errorf("expected 2 calls, got %d", callCount)
+	}
+
+	// Without a max duration timer, f is dropped and the burst ends.
+	d.Do(f)
+	d.CancelMaxWait()
+	d.CancelTrailing()
+	if d.Pending() {
+		t.Error("expected f to be dropped")
+	}
+	d.Wait()
+
+	d.Do(f)
+	d.CancelTrailing()
+	d.CancelMaxWait()
+	if d.Pending() {
+		t.Error("expected f to be dropped")
+	}
+	d.Wait()
+
+	unbounded := NewDurationDebouncer(time.Minute, 0, WithTimerClock(clock))
+	unbounded.Do(f)
+	unbounded.CancelTrailing()
+	if unbounded.Pending() {
+		t.Error("expected f to be dropped")
+	}
+	unbounded.Wait()
+	clock.Advance(time.Hour)
+	if callCount != 2 {
+		t.Errorf("expected 2 calls, got %d", callCount)
+	}
+}
+
+func TestTimeDebounceLastFireReason(t *testing.T) {
+	clock := NewManualClock(time.Now())
+	f := func() {}
+
+	d := NewDurationDebouncer(time.Minute, 90*time.Second, WithTimerClock(clock))
+	d.countLimit = 3
+
+	d.Do(f)
+	clock.Advance(time.Minute)
+	if reason := d.LastFireReason(); reason != FireTrailing {
+		t.Errorf("expected %s, got %s", FireTrailing, reason)
+	}
+
+	d.Do(f)
+	clock.Advance(50 * time.Second)
+	d.Do(f)
+	clock.Advance(40 * time.Second)
+	if reason := d.LastFireReason(); reason != FireMaxWait {
+		t.Errorf("expected %s, got %s", FireMaxWait, reason)
+	}
+
+	for i := 0; i < 3; i++ {
+		d.Do(f)
+	}
+	if reason := d.LastFireReason(); reason != FireCountLimit {
+		t.Errorf("expected %s, got %s", FireCountLimit, reason)
+	}
+
+	d.Do(f)
+	d.Flush()
+	if reason := d.LastFireReason(); reason != FireFlush {
+		t.Errorf("expected %s, got %s", FireFlush, reason)
+	}
+}
+
+func TestTimeDebounceIntervalAndMaxDuration(t *testing.T) {
+	for _, tt := range []struct {
+		name                  string
+		interval, maxDuration time.Duration
+		fireAt                time.Duration
+		reason                FireReason
+	}{
+		// The quiet period is clamped to the max duration.
+		{"IntervalLonger", 2 * time.Minute, time.Minute, time.Minute, FireMaxWait},
+		{"IntervalEqual", time.Minute, time.Minute, time.Minute, FireMaxWait},
+		// Without a max duration, only the interval applies.
+		{"NoMaxDuration", time.Minute, 0, 90 * time.Second, FireTrailing},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			callCount := 0
+			f := func() {
+				callCount++
+			}
+
+			clock := NewManualClock(time.Now())
+			d := NewDurationDebouncer(tt.interval, tt.maxDuration, WithTimerClock(clock))
+
+			// A second call half a minute in.
+			d.Do(f)
+			clock.Advance(30 * time.Second)
+			d.Do(f)
+			if callCount != 0 {
+				t.Fatalf("expected 0 calls, got %d", callCount)
+			}
+
+			clock.Advance(tt.fireAt - 30*time.Second - time.Nanosecond)
+			if callCount != 0 {
+				t.Fatalf("expected 0 calls before %s, got %d", tt.fireAt, callCount)
+			}
+			clock.Advance(time.Nanosecond)
+			if callCount != 1 {
+				t.Fatalf("expected 1 call at %s, got %d", tt.fireAt, callCount)
+			}
+			if reason := d.LastFireReason(); reason != tt.reason {
+				t.Errorf("expected %s, got %s", tt.reason, reason)
+			}
+		})
+	}
+}
+
+func TestTimeDebounceNoMaxDurationContinuousCalls(t *testing.T) {
+	callCount := 0
+	f := func() {
+		callCount++
+	}
+
+	clock := NewManualClock(time.Now())
+	d := NewDurationDebouncer(time.Minute, 0, WithTimerClock(clock))
+
+	// Calls faster than the interval keep pushing f out.
+	for i := 0; i < 200; i++ {
+		d.Do(f)
+		clock.Advance(30 * time.Second)
+	}
+	if callCount != 0 {
+		t.Errorf("expected 0 calls, got %d", callCount)
+	}
+
+	// Setting a max duration applies to the burst in progress.
+	d.SetMaxDuration(time.Hour)
+	clock.Advance(0)
+	if callCount != 1 {
+		t.Errorf("expected the elapsed max duration to execute f, got %d calls", callCount)
+	}
+}
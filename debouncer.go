@@ -0,0 +1,90 @@
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package debounce
+
+// Debouncer is implemented by the debouncers in this package, so that code
+// can accept any of them, or a fake in tests.
+type Debouncer interface {
+	// Do schedules f to be executed, superseding any pending function.
+	Do(f func())
+
+	// Flush executes the pending function, if any, right away.
+	Flush()
+
+	// Cancel drops the pending function, if any, without executing it.
+	Cancel()
+
+	// Pending reports whether a function is scheduled to be executed.
+	Pending() bool
+}
+
+var (
+	_ Debouncer = (*CountDebouncer)(nil)
+	_ Debouncer = (*DurationDebouncer)(nil)
+	_ Debouncer = passthrough{}
+	_ Debouncer = chain{}
+)
+
+// NewPassthrough returns a Debouncer which doesn't debounce at all: Do
+// executes f right away on the calling goroutine, and Flush and Cancel are
+// no-ops. It lets callers turn debouncing off, e.g. behind a feature flag,
+// without changing their call sites.
+func NewPassthrough() Debouncer {
+	return passthrough{}
+}
+
+type passthrough struct{}
+
+func (passthrough) Do(f func()) {
+	if f != nil {
+		f()
+	}
+}
+
+func (passthrough) Flush() {}
+
+func (passthrough) Cancel() {}
+
+func (passthrough) Pending() bool {
+	return false
+}
+
+// Chain returns a Debouncer which combines two policies: f is first debounced
+// by inner, and every time inner fires, f is passed on to outer's Do, e.g. to
+// debounce keystrokes and then throttle the resulting saves. inner executes
+// outer's Do without holding its lock, so the two can't deadlock.
+//
+// Flush flushes inner, then outer, so that f is executed right away. Cancel
+// cancels both, and Pending reports whether either has a pending invocation.
+func Chain(outer, inner Debouncer) Debouncer {
+	return chain{outer: outer, inner: inner}
+}
+
+type chain struct {
+	outer, inner Debouncer
+}
+
+func (c chain) Do(f func()) {
+	if f == nil {
+		c.inner.Do(nil)
+		return
+	}
+	c.inner.Do(func() {
+		c.outer.Do(f)
+	})
+}
+
+func (c chain) Flush() {
+	c.inner.Flush()
+	c.outer.Flush()
+}
+
+func (c chain) Cancel() {
+	c.inner.Cancel()
+	c.outer.Cancel()
+}
+
+func (c chain) Pending() bool {
+	return c.inner.Pending() || c.outer.Pending()
+}
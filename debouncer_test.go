@@ -0,0 +1,120 @@
+package debounce_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/qpoint-io/debounce"
+)
+
+func TestDebouncerInterface(t *testing.T) {
+	for name, d := range map[string]debounce.Debouncer{
+		"count":    debounce.NewDebouncer(time.Hour, 1000),
+		"duration": debounce.NewDurationDebouncer(time.Hour, 2*time.Hour),
+	} {
+		t.Run(name, func(t *testing.T) {
+			callCount := 0
+			d.Do(func() {
+				callCount++
+			})
+
+			if !d.Pending() {
+				t.Error("Expected a pending invocation")
+			}
+
+			d.Flush()
+			d.Do(func() {
+				callCount++
+			})
+			d.Cancel()
+
+			if d.Pending() {
+				t.Error("Expected no pending invocation")
+			}
+			if callCount != 1 {
+				t.Error("Expected count 1, was", callCount)
+			}
+		})
+	}
+}
+
+func TestPassthrough(t *testing.T) {
+	d := debounce.NewPassthrough()
+
+	callCount := 0
+	for i := 0; i < 3; i++ {
+		d.Do(func() {
+			callCount++
+		})
+		if callCount != i+1 {
+			t.Errorf("Expected count %d, was %d", i+1, callCount)
+		}
+	}
+
+	d.Do(nil)
+	d.Flush()
+	d.Cancel()
+	if d.Pending() {
+		t.Error("Expected no pending invocation")
+	}
+	if callCount != 3 {
+		t.Error("Expected count 3, was", callCount)
+	}
+}
+
+func TestChain(t *testing.T) {
+	clock := debounce.NewManualClock(time.Now())
+
+	var saved []string
+	save := func(text string) func() {
+		return func() {
+			saved = append(saved, text)
+		}
+	}
+
+	// Keystrokes are debounced for 100ms, then saves are throttled to at
+	// most one every 2s.
+	keystrokes := debounce.NewDebouncer(100*time.Millisecond, 0, debounce.WithTimerClock(clock))
+	saves := debounce.NewThrottleTrailingDebouncer(2*time.Second, debounce.WithTimerClock(clock))
+	d := debounce.Chain(saves, keystrokes)
+
+	typeText := func(text string) {
+		for i := 1; i <= len(text); i++ {
+			d.Do(save(text[:i]))
+			clock.Advance(10 * time.Millisecond)
+		}
+		clock.Advance(100 * time.Millisecond)
+	}
+
+	// The first pause saves right away.
+	typeText("hello")
+	if len(saved) != 1 || saved[0] != "hello" {
+		t.Fatalf("expected [hello], got %v", saved)
+	}
+
+	// Later pauses within the throttle interval only save the last text,
+	// at the end of the interval.
+	typeText("hello wo")
+	typeText("hello world")
+	if !d.Pending() || len(saved) != 1 {
+		t.Fatalf("expected the save to be throttled, got %v", saved)
+	}
+	clock.Advance(2 * time.Second)
+	if len(saved) != 2 || saved[1] != "hello world" {
+		t.Fatalf("expected [hello hello world], got %v", saved)
+	}
+
+	// Flush saves right away, bypassing both.
+	d.Do(save("bye"))
+	d.Flush()
+	if len(saved) != 3 || saved[2] != "bye" {
+		t.Fatalf("expected the flushed text to be saved, got %v", saved)
+	}
+
+	d.Do(save("dropped"))
+	d.Cancel()
+	clock.Advance(time.Minute)
+	if d.Pending() || len(saved) != 3 {
+		t.Errorf("expected the canceled text to be dropped, got %v", saved)
+	}
+}
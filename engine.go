@@ -0,0 +1,123 @@
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package debounce
+
+import (
+	"sync"
+	"time"
+)
+
+// timerEngine drives a debouncer's timer from a single long-lived goroutine
+// and a reused time.Timer, rather than a new time.AfterFunc every time the
+// timer is restarted. See WithReusableTimer.
+//
+// Restarting the timer only records the new deadline. As a debounced timer is
+// almost always pushed out, the goroutine is only woken up if the deadline
+// moves earlier; otherwise it notices the later deadline when the timer
+// expires and waits for the remainder.
+type timerEngine struct {
+	fire func(gen uint64)
+
+	mu      sync.Mutex
+	at      time.Time // The deadline, zero if stopped.
+	gen     uint64    // The generation passed to fire.
+	timerAt time.Time // The deadline the timer is set for, zero if none.
+
+	wake chan struct{}
+	quit chan struct{}
+	once sync.Once
+}
+
+func newTimerEngine(fire func(gen uint64)) *timerEngine {
+	e := &timerEngine{
+		fire: fire,
+		wake: make(chan struct{}, 1),
+		quit: make(chan struct{}),
+	}
+	go e.loop()
+
+	return e
+}
+
+// reset (re)starts the timer to call fire with gen after the given duration.
+func (e *timerEngine) reset(after time.Duration, gen uint64) {
+	e.mu.Lock()
+	e.at = time.Now().Add(after)
+	e.gen = gen
+	wake := e.timerAt.IsZero() || e.at.Before(e.timerAt)
+	e.mu.Unlock()
+
+	if wake {
+		select {
+		case e.wake <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// Stop stops the timer and reports whether it was active, which makes
+// timerEngine a Timer.
+func (e *timerEngine) Stop() bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	active := !e.at.IsZero()
+	e.at = time.Time{}
+	return active
+}
+
+// close stops the goroutine. It may be called more than once.
+func (e *timerEngine) close() {
+	e.once.Do(func() {
+		close(e.quit)
+	})
+}
+
+func (e *timerEngine) loop() {
+	timer := time.NewTimer(time.Hour)
+	timer.Stop()
+
+	for {
+		select {
+		case <-e.wake:
+		case <-timer.C:
+		case <-e.quit:
+			timer.Stop()
+			return
+		}
+
+		e.mu.Lock()
+		at, gen := e.at, e.gen
+		wait := time.Until(at)
+		switch {
+		case at.IsZero():
+			e.timerAt = time.Time{}
+		case wait > 0:
+			e.timerAt = at
+		default:
+			e.at, e.timerAt = time.Time{}, time.Time{}
+		}
+		e.mu.Unlock()
+
+		if at.IsZero() {
+			continue
+		}
+		if wait > 0 {
+			// The timer is only ever used by this goroutine, so it
+			// can be drained safely before being reset.
+			if !timer.Stop() {
+				select {
+				case <-timer.C:
+				default:
+				}
+			}
+			timer.Reset(wait)
+			continue
+		}
+
+		// Like time.AfterFunc, run fire on its own goroutine so that a
+		// slow f doesn't hold up the timer.
+		go e.fire(gen)
+	}
+}
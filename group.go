@@ -0,0 +1,90 @@
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package debounce
+
+import (
+	"sync"
+	"time"
+)
+
+// NewGroup returns a Group whose trigger has the same semantics as New.
+func NewGroup(after time.Duration, countLimit uint64, opts ...Option) *Group {
+	g := &Group{
+		d: NewDebouncer(after, countLimit, opts...),
+	}
+	g.run = g.fire
+
+	return g
+}
+
+// A Group fans a single debounced trigger out to several callbacks, e.g. for
+// independent consumers of the same debounced event.
+type Group struct {
+	mu        sync.Mutex
+	d         *CountDebouncer
+	callbacks []groupCallback
+	run       func()
+}
+
+type groupCallback struct {
+	f        func()
+	priority int
+}
+
+// Add registers f to be called every time the group fires, with a priority
+// of zero. It may be called concurrently with Trigger, including from a
+// callback.
+func (g *Group) Add(f func()) {
+	g.AddWithPriority(f, 0)
+}
+
+// AddWithPriority is like Add, but callbacks with a higher priority are called
+// before those with a lower one, e.g. to invalidate a cache before
+// re-rendering from it. Callbacks with the same priority are called in the
+// order they were added.
+func (g *Group) AddWithPriority(f func(), priority int) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	// Insert after the callbacks with the same or a higher priority, into a
+	// new slice so that the snapshots taken by fire are left untouched.
+	i := len(g.callbacks)
+	for i > 0 && g.callbacks[i-1].priority < priority {
+		i--
+	}
+	callbacks := make([]groupCallback, 0, len(g.callbacks)+1)
+	callbacks = append(callbacks, g.callbacks[:i]...)
+	callbacks = append(callbacks, groupCallback{f: f, priority: priority})
+	g.callbacks = append(callbacks, g.callbacks[i:]...)
+}
+
+// Trigger schedules the callbacks to be called once the group stops being
+// triggered for the configured duration.
+func (g *Group) Trigger() {
+	g.d.Do(g.run)
+}
+
+// Flush calls the callbacks right away if a trigger is pending.
+func (g *Group) Flush() {
+	g.d.Flush()
+}
+
+// Close drops the pending trigger, if any. Subsequent triggers are no-ops.
+func (g *Group) Close() {
+	g.d.Close()
+}
+
+// fire calls the callbacks registered so far in priority order, without
+// holding g.mu.
+func (g *Group) fire() {
+	// AddWithPriority never modifies the slice in place, so it is a
+	// snapshot.
+	g.mu.Lock()
+	callbacks := g.callbacks
+	g.mu.Unlock()
+
+	for _, c := range callbacks {
+		c.f()
+	}
+}
@@ -0,0 +1,71 @@
+package debounce_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/qpoint-io/debounce"
+)
+
+func TestGroup(t *testing.T) {
+	clock := debounce.NewManualClock(time.Now())
+	g := debounce.NewGroup(time.Second, 1000, debounce.WithTimerClock(clock))
+
+	var calls []string
+	g.Add(func() {
+		calls = append(calls, "a")
+		// Callbacks added while firing are called from the next time on.
+		g.Add(func() {
+			calls = append(calls, "c")
+		})
+	})
+	g.Add(func() {
+		calls = append(calls, "b")
+	})
+
+	for i := 0; i < 10; i++ {
+		g.Trigger()
+	}
+	clock.Advance(time.Second)
+
+	if got := len(calls); got != 2 || calls[0] != "a" || calls[1] != "b" {
+		t.Errorf("expected [a b], got %v", calls)
+	}
+
+	g.Trigger()
+	g.Flush()
+
+	if got := len(calls); got != 5 || calls[4] != "c" {
+		t.Errorf("expected [a b a b c], got %v", calls)
+	}
+}
+
+func TestGroupPriority(t *testing.T) {
+	clock := debounce.NewManualClock(time.Now())
+	g := debounce.NewGroup(time.Second, 1000, debounce.WithTimerClock(clock))
+
+	var calls []string
+	add := func(name string, priority int) {
+		g.AddWithPriority(func() {
+			calls = append(calls, name)
+		}, priority)
+	}
+	add("render", 0)
+	add("invalidate", 10)
+	add("log", -1)
+	add("render2", 0)
+	add("invalidate2", 10)
+
+	g.Trigger()
+	clock.Advance(time.Second)
+
+	expected := []string{"invalidate", "invalidate2", "render", "render2", "log"}
+	if len(calls) != len(expected) {
+		t.Fatalf("expected %v, got %v", expected, calls)
+	}
+	for i := range expected {
+		if calls[i] != expected[i] {
+			t.Fatalf("expected %v, got %v", expected, calls)
+		}
+	}
+}
@@ -0,0 +1,158 @@
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package debounce
+
+import (
+	"math"
+	"slices"
+	"sync"
+	"time"
+)
+
+// NewKeyed returns a debounced function that debounces each key independently,
+// with the same semantics as New. Once the burst of a key ends, whether its
+// function was executed or the burst was dropped, e.g. by WithMinCount, the
+// key's state is released.
+//
+// The options apply to the debouncer of every key, so WithMaxWait makes sure
+// a key that keeps being touched is still executed in time. WithMaxKeys
+// bounds the number of keys pending at once. WithRegistry and
+// WithReusableTimer are ignored, as the debouncers of the keys are internal,
+// and so are WithCooldown, WithRateLimit and WithOnce, as no state is kept
+// for a key between its bursts.
+func NewKeyed[K comparable](after time.Duration, countLimit uint64, opts ...Option) func(key K, f func()) {
+	return NewKeyedDebouncer[K](after, countLimit, opts...).Do
+}
+
+// NewKeyedDebouncer returns a Keyed debouncer with the same semantics as
+// NewKeyed. Unlike the bare function, it can also be drained.
+func NewKeyedDebouncer[K comparable](after time.Duration, countLimit uint64, opts ...Option) *Keyed[K] {
+	return &Keyed[K]{
+		after:      after,
+		countLimit: countLimit,
+		maxKeys:    newConfig(opts).maxKeys,
+		opts:       append(slices.Clip(opts), perBurst()),
+		debouncers: make(map[K]*CountDebouncer),
+	}
+}
+
+// NewKeyedByArg is like NewKeyed, but the key is derived from the argument
+// of the debounced function by keyFn. The last argument for each key is passed
+// on to f once the debounced function stops being called with that key for
+// the given duration, e.g. the last change event for each file name.
+func NewKeyedByArg[T any, K comparable](keyFn func(T) K, after time.Duration) func(arg T, f func(T)) {
+	k := NewKeyedDebouncer[K](after, math.MaxUint64)
+
+	return func(arg T, f func(T)) {
+		k.Do(keyFn(arg), func() {
+			f(arg)
+		})
+	}
+}
+
+// Keyed is a debouncer which debounces each key independently.
+// See NewKeyed for the semantics of Do.
+type Keyed[K comparable] struct {
+	mu         sync.Mutex
+	after      time.Duration
+	countLimit uint64
+	maxKeys    int
+	opts       []Option
+	debouncers map[K]*CountDebouncer
+}
+
+// Do schedules f to be called for the given key, see NewKeyed.
+func (k *Keyed[K]) Do(key K, f func()) {
+	var oldest *CountDebouncer
+
+	k.mu.Lock()
+	d, ok := k.debouncers[key]
+	if !ok {
+		if k.maxKeys > 0 && len(k.debouncers) >= k.maxKeys {
+			oldest = k.removeOldest()
+		}
+		d = NewDebouncer(k.after, k.countLimit, k.opts...)
+		d.onIdle = func() {
+			k.evict(key, d)
+		}
+		k.debouncers[key] = d
+	}
+	// The key's debouncer is only ever added to while holding k.mu, so it
+	// can't be evicted in between.
+	run, hook := d.add(f)
+	k.mu.Unlock()
+
+	// Make room for key by executing the oldest key's function right away.
+	if oldest != nil {
+		oldest.CloseFlush()
+	}
+	if hook != nil {
+		hook()
+	}
+	if run != nil {
+		// Reached the count limit.
+		d.execute(run)
+		k.evict(key, d)
+	}
+}
+
+// DrainAll executes the pending function of every key right away and
+// releases all keys. Calls to Do made meanwhile, including from the executed
+// functions, are debounced as usual.
+func (k *Keyed[K]) DrainAll() {
+	k.mu.Lock()
+	debouncers := k.debouncers
+	k.debouncers = make(map[K]*CountDebouncer)
+	k.mu.Unlock()
+
+	for _, d := range debouncers {
+		d.CloseFlush()
+	}
+}
+
+// removeOldest removes the debouncer whose burst started first and returns
+// it, so that it can be flushed without holding k.mu.
+func (k *Keyed[K]) removeOldest() *CountDebouncer {
+	var (
+		oldestKey   K
+		oldest      *CountDebouncer
+		oldestStart time.Time
+	)
+	for key, d := range k.debouncers {
+		d.mu.Lock()
+		start := d.start
+		d.mu.Unlock()
+
+		if oldest == nil || start.Before(oldestStart) {
+			oldestKey, oldest, oldestStart = key, d, start
+		}
+	}
+	delete(k.debouncers, oldestKey)
+	return oldest
+}
+
+// evict removes and closes the debouncer for key unless it has been rearmed
+// since its burst ended.
+func (k *Keyed[K]) evict(key K, d *CountDebouncer) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	if k.debouncers[key] == d && !d.Pending() {
+		delete(k.debouncers, key)
+		d.Close()
+	}
+}
+
+// perBurst drops the options that the internal debouncers of a keyed
+// debouncer can't honor, as they only live for a single burst: those tying a
+// debouncer's lifecycle to the caller and those spanning several bursts.
+func perBurst() Option {
+	return func(c *config) {
+		c.registry = nil
+		c.reusableTimer = false
+		c.cooldown = 0
+		c.rateLimit = 0
+		c.once = false
+	}
+}
@@ -0,0 +1,252 @@
+package debounce
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestKeyed(t *testing.T) {
+	var (
+		mu     sync.Mutex
+		counts = make(map[string]int)
+	)
+
+	f := func(key string) func() {
+		return func() {
+			mu.Lock()
+			defer mu.Unlock()
+			counts[key]++
+		}
+	}
+
+	k := NewKeyedDebouncer[string](50*time.Millisecond, 1000)
+
+	var wg sync.WaitGroup
+	for _, key := range []string{"a", "b", "c"} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 10; i++ {
+				k.Do(key, f(key))
+			}
+		}()
+	}
+	wg.Wait()
+
+	time.Sleep(100 * time.Millisecond)
+
+	mu.Lock()
+	for _, key := range []string{"a", "b", "c"} {
+		if counts[key] != 1 {
+			t.Errorf("expected 1 call for %q, got %d", key, counts[key])
+		}
+	}
+	mu.Unlock()
+
+	k.mu.Lock()
+	if n := len(k.debouncers); n != 0 {
+		t.Errorf("expected all keys to be released, got %d", n)
+	}
+	k.mu.Unlock()
+}
+
+func TestKeyedByArg(t *testing.T) {
+	type event struct {
+		name string
+		op   int
+	}
+
+	var (
+		mu   sync.Mutex
+		last = make(map[string]int)
+	)
+
+	f := func(e event) {
+		mu.Lock()
+		defer mu.Unlock()
+		if _, ok := last[e.name]; ok {
+			t.Errorf("expected a single call for %q", e.name)
+		}
+		last[e.name] = e.op
+	}
+
+	debounced := NewKeyedByArg(func(e event) string { return e.name }, 50*time.Millisecond)
+	for i := 0; i < 10; i++ {
+		debounced(event{"a.txt", i}, f)
+		debounced(event{"b.txt", i * 2}, f)
+	}
+
+	time.Sleep(100 * time.Millisecond)
+
+	mu.Lock()
+	defer mu.Unlock()
+	if last["a.txt"] != 9 || last["b.txt"] != 18 {
+		t.Errorf("expected the last event for each key, got %v", last)
+	}
+}
+
+func TestKeyedDrainAll(t *testing.T) {
+	var (
+		mu     sync.Mutex
+		counts = make(map[string]int)
+	)
+
+	k := NewKeyedDebouncer[string](50*time.Millisecond, 1000)
+
+	keys := []string{"a", "b", "c", "d"}
+	for _, key := range keys {
+		for i := 0; i < 3; i++ {
+			k.Do(key, func() {
+				mu.Lock()
+				counts[key]++
+				mu.Unlock()
+
+				// Enqueuing new work from a drained function must not deadlock.
+				if key == "a" {
+					k.Do("a", func() {})
+				}
+			})
+		}
+	}
+
+	k.DrainAll()
+
+	mu.Lock()
+	for _, key := range keys {
+		if counts[key] != 1 {
+			t.Errorf("expected 1 call for %q, got %d", key, counts[key])
+		}
+	}
+	mu.Unlock()
+
+	time.Sleep(100 * time.Millisecond)
+
+	mu.Lock()
+	for _, key := range keys {
+		if counts[key] != 1 {
+			t.Errorf("expected no more calls for %q after draining, got %d", key, counts[key])
+		}
+	}
+	mu.Unlock()
+}
+
+func TestKeyedMaxKeysAndMaxWait(t *testing.T) {
+	clock := NewManualClock(time.Now())
+	k := NewKeyedDebouncer[int](time.Minute, 0, WithTimerClock(clock), WithMaxKeys(3), WithMaxWait(5*time.Minute))
+
+	var calls []int
+	for key := 0; key < 10; key++ {
+		k.Do(key, func() {
+			calls = append(calls, key)
+		})
+		clock.Advance(time.Second)
+
+		if n := len(k.debouncers); n > 3 {
+			t.Fatalf("expected at most 3 keys, got %d", n)
+		}
+	}
+
+	// The oldest keys were executed to make room, in order.
+	if len(calls) != 7 {
+		t.Fatalf("expected 7 calls, got %v", calls)
+	}
+	for i, key := range calls {
+		if key != i {
+			t.Fatalf("expected the oldest keys to be executed first, got %v", calls)
+		}
+	}
+
+	clock.Advance(time.Minute)
+	if len(calls) != 10 || len(k.debouncers) != 0 {
+		t.Fatalf("expected all keys to be executed and released, got %v", calls)
+	}
+
+	// A key that keeps being touched is still executed within the max wait.
+	for i := 0; i < 10; i++ {
+		k.Do(42, func() {
+			calls = append(calls, 42)
+		})
+		clock.Advance(30 * time.Second)
+	}
+	if len(calls) != 11 || calls[10] != 42 {
+		t.Errorf("expected the touched key to be executed, got %v", calls)
+	}
+}
+
+func TestKeyedReleasesKeys(t *testing.T) {
+	for _, tt := range []struct {
+		name  string
+		opts  []Option
+		calls int
+	}{
+		{"Trailing", nil, 3},
+		// Bursts dropped for having too few calls.
+		{"MinCount", []Option{WithMinCount(5)}, 3},
+		// The leading call executes f, the burst ends with nothing pending.
+		{"Leading", []Option{WithLeading()}, 3},
+		{"CountLimit", []Option{WithCountLimit(3)}, 3},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			clock := NewManualClock(time.Now())
+			k := NewKeyedDebouncer[int](time.Second, 0, append(tt.opts, WithTimerClock(clock))...)
+
+			for key := 0; key < 100; key++ {
+				for i := 0; i < tt.calls; i++ {
+					k.Do(key, func() {})
+				}
+			}
+			clock.Advance(time.Second)
+
+			k.mu.Lock()
+			defer k.mu.Unlock()
+			if n := len(k.debouncers); n != 0 {
+				t.Errorf("expected all keys to be released, got %d", n)
+			}
+		})
+	}
+}
+
+func TestKeyedIgnoresLifecycleOptions(t *testing.T) {
+	var r Registry
+	clock := NewManualClock(time.Now())
+	k := NewKeyedDebouncer[int](time.Second, 0, WithTimerClock(clock), WithRegistry(&r), WithReusableTimer())
+
+	for key := 0; key < 100; key++ {
+		k.Do(key, func() {})
+	}
+
+	k.mu.Lock()
+	for key, d := range k.debouncers {
+		if d.engine != nil {
+			t.Fatalf("expected key %d not to start a timer goroutine", key)
+		}
+	}
+	k.mu.Unlock()
+	if n := r.Len(); n != 0 {
+		t.Errorf("expected no registered debouncers, got %d", n)
+	}
+
+	clock.Advance(time.Second)
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if n := len(k.debouncers); n != 0 {
+		t.Errorf("expected all keys to be released, got %d", n)
+	}
+}
+
+func TestKeyedIgnoresCrossBurstOptions(t *testing.T) {
+	clock := NewManualClock(time.Now())
+	k := NewKeyedDebouncer[int](time.Second, 0, WithTimerClock(clock), WithCooldown(time.Hour), WithRateLimit(1, time.Hour), WithOnce())
+
+	calls := 0
+	for i := 0; i < 3; i++ {
+		k.Do(1, func() {
+			calls++
+		})
+		clock.Advance(time.Second)
+	}
+	if calls != 3 {
+		t.Errorf("expected every burst to be executed, got %d calls", calls)
+	}
+}
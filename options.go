@@ -0,0 +1,298 @@
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package debounce
+
+import (
+	"math"
+	"math/rand/v2"
+	"slices"
+	"time"
+)
+
+// An Option configures a debouncer. Options other than WithName, WithRegistry,
+// WithClock and WithTimerClock only apply to the count-based CountDebouncer,
+// and WithMaxKeys only to keyed debouncers.
+type Option func(*config)
+
+type config struct {
+	name          string
+	registry      *Registry
+	now           func() time.Time
+	afterFunc     func(time.Duration, func()) Timer
+	after         time.Duration
+	maxWait       time.Duration
+	maxJitter     time.Duration
+	rand          *rand.Rand
+	backoffFactor float64
+	maxInterval   time.Duration
+	adaptiveMin   time.Duration
+	adaptiveMax   time.Duration
+	countLimit    uint64
+	minCount      uint64
+	leading       bool
+	skipIdentical bool
+	noOverlap     bool
+	once          bool
+	rateLimit     int
+	ratePer       time.Duration
+	cooldown      time.Duration
+	executor      func(task func())
+	dispatcher    func(task func())
+	maxKeys       int
+	reusableTimer bool
+}
+
+func newConfig(opts []Option) config {
+	c := config{
+		now:        now,
+		afterFunc:  afterFunc,
+		countLimit: math.MaxUint64,
+	}
+	for _, opt := range opts {
+		opt(&c)
+	}
+	return c
+}
+
+// WithName sets the name of the debouncer, as returned by its Name method,
+// e.g. to tag the logs and metrics of its hooks when running many debouncers.
+func WithName(name string) Option {
+	return func(c *config) {
+		c.name = name
+	}
+}
+
+// WithRegistry adds the debouncer to r until it is closed, so that it can be
+// flushed or closed along with every other debouncer in r.
+func WithRegistry(r *Registry) Option {
+	return func(c *config) {
+		c.registry = r
+	}
+}
+
+// WithClock sets the function used by the debouncer to get the current time.
+// It defaults to time.Now, and is mostly useful to control time in tests.
+func WithClock(now func() time.Time) Option {
+	return func(c *config) {
+		c.now = now
+	}
+}
+
+// WithTimerClock sets the clock used by the debouncer both to get the current
+// time and to schedule its timers, e.g. a ManualClock in tests.
+func WithTimerClock(clock Clock) Option {
+	return func(c *config) {
+		c.now = clock.Now
+		c.afterFunc = clock.AfterFunc
+	}
+}
+
+// WithAfter sets the duration the debounced function has to stop being called
+// for before f is executed.
+func WithAfter(after time.Duration) Option {
+	return func(c *config) {
+		c.after = after
+	}
+}
+
+// WithCountLimit sets the number of calls of a burst on which f is executed
+// right away. By default there is no limit, and a countLimit of zero means no limit
+// too.
+func WithCountLimit(countLimit uint64) Option {
+	return func(c *config) {
+		c.countLimit = noLimit(countLimit)
+	}
+}
+
+// noLimit maps a count limit of zero to no limit.
+func noLimit(countLimit uint64) uint64 {
+	if countLimit == 0 {
+		return math.MaxUint64
+	}
+	return countLimit
+}
+
+// WithMinCount drops bursts of fewer than minCount calls: when the quiet
+// period ends, f is only executed if the debounced function was called at
+// least minCount times. The count limit still executes f early, and Flush
+// executes the pending function regardless. It doesn't apply to WithLeading.
+func WithMinCount(minCount uint64) Option {
+	return func(c *config) {
+		c.minCount = minCount
+	}
+}
+
+// WithLeading executes f on the first call of a burst instead of at the end
+// of it, see NewLeading.
+func WithLeading() Option {
+	return func(c *config) {
+		c.leading = true
+	}
+}
+
+// WithSkipIdentical keeps the timer running rather than restarting it when f
+// is the same function as the pending one, so that a stream of identical calls
+// can't push the execution out indefinitely. Functions are compared by their
+// code pointer, so closures created by the same function literal are
+// considered identical even if they capture different variables.
+func WithSkipIdentical() Option {
+	return func(c *config) {
+		c.skipIdentical = true
+	}
+}
+
+// WithNoOverlap makes sure f is never executed while the previously executed
+// function is still running, e.g. when f can take longer than the quiet
+// period. An execution colliding with a running function, whether fired by
+// the timer, the count limit or Flush, is deferred rather than dropped: the
+// pending function at that point is executed right after the running one
+// returns, once, with calls made meanwhile still coalesced into it.
+func WithNoOverlap() Option {
+	return func(c *config) {
+		c.noOverlap = true
+	}
+}
+
+// WithOnce closes the debouncer once a function has been executed, so that f
+// is executed at most once ever, as if Close was called then: Done is closed
+// and the debouncer leaves its registry. Canceling the pending invocation
+// before it fires doesn't count.
+func WithOnce() Option {
+	return func(c *config) {
+		c.once = true
+	}
+}
+
+// WithReusableTimer drives the timer from a single long-lived goroutine and a
+// reused time.Timer instead of a new time.AfterFunc every time the timer is
+// restarted, which saves allocations when the debounced function is called at
+// a very high rate. The goroutine lives until the debouncer is closed, so
+// such a debouncer must be closed with Close once it is no longer needed. The
+// timer always runs on real time, regardless of WithTimerClock.
+//
+// Wrappers that can't be closed, such as New, NewBatch, NewCountMap,
+// NewWithArg and NewResult, ignore this option.
+// DebounceChannel closes its debouncer once the input channel is closed.
+func WithReusableTimer() Option {
+	return func(c *config) {
+		c.reusableTimer = true
+	}
+}
+
+// WithMaxWait sets the maximum duration f can be delayed for from the first
+// call of a burst, even if the debounced function keeps being called.
+func WithMaxWait(maxWait time.Duration) Option {
+	return func(c *config) {
+		c.maxWait = maxWait
+	}
+}
+
+// WithJitter adds a random duration in [0, maxJitter) to the quiet period
+// every time it is (re)started, so that debouncers started at the same time
+// don't all fire at once.
+func WithJitter(maxJitter time.Duration) Option {
+	return func(c *config) {
+		c.maxJitter = maxJitter
+	}
+}
+
+// WithRand sets the source of randomness used for jitter. It defaults to the
+// global source of math/rand/v2. r is only used while holding the
+// debouncer's lock, so it must not be shared with other debouncers or
+// goroutines.
+func WithRand(r *rand.Rand) Option {
+	return func(c *config) {
+		c.rand = r
+	}
+}
+
+// WithBackoff multiplies the quiet period by factor every time it is
+// restarted by a new call, up to maxInterval, so that a sustained stream of
+// calls is coalesced more aggressively. The quiet period goes back to its
+// initial duration once f has been executed. A maxInterval shorter than the
+// quiet period disables the backoff rather than shortening the quiet period.
+func WithBackoff(factor float64, maxInterval time.Duration) Option {
+	return func(c *config) {
+		c.backoffFactor = factor
+		c.maxInterval = maxInterval
+	}
+}
+
+// WithAdaptive adapts the quiet period to how frequently the debounced
+// function is called, instead of using a fixed duration: it grows toward
+// maxAfter while calls are dense, coalescing more of them, and shrinks toward
+// minAfter while they are sparse, executing f sooner. The quiet period is
+// minAfter+maxAfter minus an exponential moving average of the time between
+// calls, clamped to [minAfter, maxAfter]. It takes precedence over the
+// configured quiet period and WithBackoff.
+func WithAdaptive(minAfter, maxAfter time.Duration) Option {
+	return func(c *config) {
+		c.adaptiveMin = minAfter
+		c.adaptiveMax = maxAfter
+	}
+}
+
+// WithRateLimit caps the number of times f is executed to maxExec within any
+// rolling window of the given duration. Once the cap is reached, firing is
+// deferred until the oldest execution in the window has left it, so that
+// frequent but well-spaced bursts can't overwhelm a downstream service.
+// Flush still executes the pending function right away.
+func WithRateLimit(maxExec int, per time.Duration) Option {
+	return func(c *config) {
+		c.rateLimit = maxExec
+		c.ratePer = per
+	}
+}
+
+// WithCooldown drops every call made within cooldown of an execution, so
+// that executing f can't immediately start another burst, e.g. when f itself
+// causes the events being debounced. Unlike a throttle, the cooldown starts
+// when f is executed rather than on the first call of a burst. Dropped calls
+// are counted in Stats.
+func WithCooldown(cooldown time.Duration) Option {
+	return func(c *config) {
+		c.cooldown = cooldown
+	}
+}
+
+// WithExecutor routes the execution of f when the timer fires through
+// executor, e.g. to run it on a bounded worker pool. By default, f is executed
+// on the timer's own goroutine. Calls executing f synchronously, such as Flush
+// or a call reaching the count limit, still execute it on the calling
+// goroutine.
+func WithExecutor(executor func(task func())) Option {
+	return func(c *config) {
+		c.executor = executor
+	}
+}
+
+// WithDispatch hands every execution of f off to dispatch, e.g. to run it on
+// a GUI's main loop when f must run on a specific goroutine. Unlike
+// WithExecutor, it also applies to executions that are otherwise synchronous,
+// such as Flush or a call reaching the count limit, which then return without
+// waiting for f. Wait must not be called from the goroutine dispatch runs
+// tasks on, as it would wait for a task that can't run.
+func WithDispatch(dispatch func(task func())) Option {
+	return func(c *config) {
+		c.dispatcher = dispatch
+	}
+}
+
+// WithMaxKeys bounds the number of keys a keyed debouncer keeps pending at
+// once. When a new key would exceed it, the function of the key whose burst
+// started first is executed right away to make room. By default there is no
+// limit.
+func WithMaxKeys(maxKeys int) Option {
+	return func(c *config) {
+		c.maxKeys = maxKeys
+	}
+}
+
+// unclosable drops WithReusableTimer from opts, for wrappers which never
+// close their debouncer.
+func unclosable(opts []Option) []Option {
+	return append(slices.Clip(opts), func(c *config) {
+		c.reusableTimer = false
+	})
+}
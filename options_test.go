@@ -0,0 +1,786 @@
+package debounce_test
+
+import (
+	"math/rand/v2"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/qpoint-io/debounce"
+)
+
+type testClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *testClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *testClock) Add(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+func TestWithClock(t *testing.T) {
+	clock := &testClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+
+	callCount := 0
+	f := func() {
+		callCount++
+	}
+
+	d := debounce.NewDurationDebouncer(time.Hour, time.Minute, debounce.WithClock(clock.Now))
+
+	d.Do(f)
+	clock.Add(30 * time.Second)
+	d.Do(f)
+
+	if callCount != 0 {
+		t.Errorf("expected 0 calls, got %d", callCount)
+	}
+
+	// The max duration has passed according to the injected clock.
+	clock.Add(30 * time.Second)
+	d.Do(f)
+
+	if callCount != 1 {
+		t.Errorf("expected 1 call, got %d", callCount)
+	}
+	if d.Pending() {
+		t.Error("expected no pending invocation")
+	}
+}
+
+func TestNewWithOptions(t *testing.T) {
+	var counter uint64
+
+	f := func() {
+		atomic.AddUint64(&counter, 1)
+	}
+
+	d := debounce.NewWithOptions(
+		debounce.WithAfter(50*time.Millisecond),
+		debounce.WithCountLimit(2),
+	)
+
+	// The second call reaches the count limit.
+	for i := 0; i < 2; i++ {
+		d.Do(f)
+	}
+
+	if c := atomic.LoadUint64(&counter); c != 1 {
+		t.Error("Expected count 1, was", c)
+	}
+
+	// The next call starts a new burst, executed once it goes quiet.
+	d.Do(f)
+	if c := atomic.LoadUint64(&counter); c != 1 || !d.Pending() {
+		t.Error("Expected a pending execution, was", c)
+	}
+	time.Sleep(100 * time.Millisecond)
+
+	if c := atomic.LoadUint64(&counter); c != 2 {
+		t.Error("Expected count 2, was", c)
+	}
+}
+
+func TestWithLeading(t *testing.T) {
+	var counter uint64
+
+	f := func() {
+		atomic.AddUint64(&counter, 1)
+	}
+
+	d := debounce.NewWithOptions(debounce.WithAfter(50*time.Millisecond), debounce.WithLeading())
+	for i := 0; i < 10; i++ {
+		d.Do(f)
+	}
+
+	if c := atomic.LoadUint64(&counter); c != 1 {
+		t.Error("Expected count 1, was", c)
+	}
+	d.Wait()
+}
+
+func TestWithMaxWait(t *testing.T) {
+	var counter uint64
+
+	f := func() {
+		atomic.AddUint64(&counter, 1)
+	}
+
+	d := debounce.NewWithOptions(
+		debounce.WithAfter(100*time.Millisecond),
+		debounce.WithMaxWait(150*time.Millisecond),
+	)
+
+	// The calls keep coming faster than the quiet period, so only the max
+	// wait executes f.
+	for i := 0; i < 8; i++ {
+		d.Do(f)
+		time.Sleep(50 * time.Millisecond)
+	}
+	d.Cancel()
+
+	if c := atomic.LoadUint64(&counter); c < 2 || c > 3 {
+		t.Error("Expected count 2 or 3, was", c)
+	}
+}
+
+func TestWithJitter(t *testing.T) {
+	const (
+		after     = 20 * time.Millisecond
+		maxJitter = 100 * time.Millisecond
+	)
+
+	// The same seed yields the same jitter.
+	jitter := time.Duration(rand.New(rand.NewPCG(1, 2)).Int64N(int64(maxJitter)))
+
+	fired := make(chan time.Time, 1)
+
+	d := debounce.NewWithOptions(
+		debounce.WithAfter(after),
+		debounce.WithJitter(maxJitter),
+		debounce.WithRand(rand.New(rand.NewPCG(1, 2))),
+	)
+
+	start := time.Now()
+	d.Do(func() {
+		fired <- time.Now()
+	})
+
+	if elapsed := (<-fired).Sub(start); elapsed < after+jitter || elapsed > after+maxJitter+50*time.Millisecond {
+		t.Errorf("Expected f to fire after %s, was %s", after+jitter, elapsed)
+	}
+}
+
+func TestWithBackoff(t *testing.T) {
+	fired := make(chan time.Time, 1)
+
+	d := debounce.NewWithOptions(
+		debounce.WithAfter(20*time.Millisecond),
+		debounce.WithBackoff(2, 100*time.Millisecond),
+	)
+
+	// Each call doubles the quiet period: 20ms, 40ms, 80ms, then capped at 100ms.
+	for i := 0; i < 4; i++ {
+		d.Do(func() {})
+	}
+
+	start := time.Now()
+	d.Do(func() {
+		fired <- time.Now()
+	})
+
+	if elapsed := (<-fired).Sub(start); elapsed < 100*time.Millisecond {
+		t.Error("Expected the quiet period to back off to 100ms, was", elapsed)
+	}
+
+	// The quiet period is back to its initial duration after firing.
+	start = time.Now()
+	d.Do(func() {
+		fired <- time.Now()
+	})
+
+	if elapsed := (<-fired).Sub(start); elapsed > 80*time.Millisecond {
+		t.Error("Expected the quiet period to be reset to 20ms, was", elapsed)
+	}
+}
+
+func TestWithBackoffBelowAfter(t *testing.T) {
+	clock := debounce.NewManualClock(time.Now())
+
+	var counter int
+	d := debounce.NewDebouncer(time.Second, 0,
+		debounce.WithTimerClock(clock),
+		debounce.WithBackoff(2, 0),
+	)
+
+	// A max interval below the quiet period doesn't shorten it.
+	for i := 0; i < 3; i++ {
+		d.Do(func() {
+			counter++
+		})
+	}
+	clock.Advance(999 * time.Millisecond)
+	if counter != 0 {
+		t.Errorf("expected no call before the quiet period, got %d", counter)
+	}
+	clock.Advance(time.Millisecond)
+	if counter != 1 {
+		t.Errorf("expected 1 call after the quiet period, got %d", counter)
+	}
+}
+
+func TestWithMinCount(t *testing.T) {
+	var counter uint64
+
+	f := func() {
+		atomic.AddUint64(&counter, 1)
+	}
+
+	d := debounce.NewWithOptions(
+		debounce.WithAfter(20*time.Millisecond),
+		debounce.WithMinCount(3),
+	)
+
+	// A burst of two calls is dropped as noise.
+	d.Do(f)
+	d.Do(f)
+	d.Wait()
+
+	if c := atomic.LoadUint64(&counter); c != 0 {
+		t.Error("Expected count 0, was", c)
+	}
+
+	for i := 0; i < 3; i++ {
+		d.Do(f)
+	}
+	d.Wait()
+
+	if c := atomic.LoadUint64(&counter); c != 1 {
+		t.Error("Expected count 1, was", c)
+	}
+}
+
+func TestWithMinCountLeading(t *testing.T) {
+	clock := debounce.NewManualClock(time.Now())
+
+	counter := 0
+	f := func() {
+		counter++
+	}
+
+	// The min count doesn't apply to the trailing execution of leading modes.
+	d := debounce.NewThrottleTrailingDebouncer(time.Minute, debounce.WithTimerClock(clock), debounce.WithMinCount(2))
+	for i := 0; i < 5; i++ {
+		d.Do(f)
+	}
+	clock.Advance(time.Minute)
+	if counter != 2 {
+		t.Errorf("expected 2 calls, got %d", counter)
+	}
+
+	// A single call in the next interval is executed too.
+	d.Do(f)
+	clock.Advance(time.Minute)
+	if counter != 3 {
+		t.Errorf("expected 3 calls, got %d", counter)
+	}
+}
+
+func TestWithRateLimit(t *testing.T) {
+	d := debounce.NewDebouncer(10*time.Millisecond, 1000, debounce.WithRateLimit(2, 300*time.Millisecond))
+
+	// Three bursts which would each fire well within the window.
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		d.Do(func() {})
+		time.Sleep(40 * time.Millisecond)
+	}
+
+	if n := d.Executed(); n != 2 {
+		t.Errorf("expected 2 executions within the window, got %d", n)
+	}
+	if !d.Pending() {
+		t.Error("expected the third burst to be deferred")
+	}
+
+	d.Wait()
+
+	if n := d.Executed(); n != 3 {
+		t.Errorf("expected 3 executions once the window has passed, got %d", n)
+	}
+	if elapsed := d.Stats().LastExecution.Sub(start); elapsed < 300*time.Millisecond {
+		t.Errorf("expected the third execution to wait for the window, was after %s", elapsed)
+	}
+}
+
+func TestWithClockParallel(t *testing.T) {
+	// Each debouncer uses its own clock, so tests controlling time don't
+	// interfere with each other.
+	for _, offset := range []time.Duration{time.Minute, 3 * time.Minute} {
+		t.Run(offset.String(), func(t *testing.T) {
+			t.Parallel()
+
+			clock := &testClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+
+			callCount := 0
+			d := debounce.NewDurationDebouncer(time.Hour, 2*time.Minute, debounce.WithClock(clock.Now))
+			for i := 0; i < 10; i++ {
+				d.Do(func() {
+					callCount++
+				})
+				clock.Add(offset / 10)
+				time.Sleep(time.Millisecond)
+			}
+			d.Do(func() {
+				callCount++
+			})
+
+			// Only the clock advanced past the max duration fires.
+			want := 0
+			if offset > 2*time.Minute {
+				want = 1
+			}
+			if callCount != want {
+				t.Errorf("expected %d calls, got %d", want, callCount)
+			}
+			d.Cancel()
+		})
+	}
+}
+
+func TestWithDispatch(t *testing.T) {
+	clock := debounce.NewManualClock(time.Now())
+
+	// The main loop of the test runs the queued tasks.
+	var queue []func()
+	dispatch := func(task func()) {
+		queue = append(queue, task)
+	}
+	runQueue := func() {
+		for len(queue) > 0 {
+			task := queue[0]
+			queue = queue[1:]
+			task()
+		}
+	}
+
+	var calls []string
+	record := func(name string) func() {
+		return func() {
+			calls = append(calls, name)
+		}
+	}
+
+	d := debounce.NewDebouncer(time.Second, 3, debounce.WithTimerClock(clock), debounce.WithDispatch(dispatch))
+
+	// The timer, Flush and the count limit all hand f off.
+	d.Do(record("timer"))
+	clock.Advance(time.Second)
+	d.Do(record("flush"))
+	d.Flush()
+	for i := 0; i < 3; i++ {
+		d.Do(record("limit"))
+	}
+	if len(calls) != 0 {
+		t.Errorf("expected no calls before the queue is run, got %v", calls)
+	}
+	if len(queue) != 3 {
+		t.Errorf("expected 3 queued tasks, got %d", len(queue))
+	}
+
+	runQueue()
+	d.Wait()
+	if len(calls) != 3 || calls[0] != "timer" || calls[1] != "flush" || calls[2] != "limit" {
+		t.Errorf("expected [timer flush limit], got %v", calls)
+	}
+}
+
+func TestWithExecutor(t *testing.T) {
+	var executed atomic.Int32
+	tasks := make(chan func(), 10)
+	executor := func(task func()) {
+		tasks <- task
+	}
+
+	// A single worker executes the functions of all keys.
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for task := range tasks {
+			task()
+			executed.Add(1)
+		}
+	}()
+
+	var wg sync.WaitGroup
+	wg.Add(5)
+	debounced := debounce.NewKeyed[int](10*time.Millisecond, 1000, debounce.WithExecutor(executor))
+	for key := 0; key < 5; key++ {
+		debounced(key, wg.Done)
+	}
+
+	wg.Wait()
+	close(tasks)
+	<-done
+
+	if n := executed.Load(); n != 5 {
+		t.Errorf("expected 5 tasks executed by the worker, got %d", n)
+	}
+}
+
+func TestWithName(t *testing.T) {
+	d := debounce.NewDebouncer(time.Hour, 1000, debounce.WithName("flush"))
+
+	var executed []string
+	d.OnExecute(func() {
+		executed = append(executed, d.Name())
+	})
+	d.Do(func() {})
+	d.Flush()
+
+	if len(executed) != 1 || executed[0] != "flush" {
+		t.Errorf("expected the hook to see the name, got %v", executed)
+	}
+
+	if name := debounce.NewDurationDebouncer(time.Hour, time.Hour).Name(); name != "" {
+		t.Errorf("expected no name by default, got %q", name)
+	}
+}
+
+func TestWithSkipIdentical(t *testing.T) {
+	clock := debounce.NewManualClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+
+	callCount := 0
+	f := func() {
+		callCount++
+	}
+
+	d := debounce.NewDebouncer(time.Minute, 1000, debounce.WithTimerClock(clock), debounce.WithSkipIdentical())
+
+	// Identical calls don't push the deadline out.
+	for i := 0; i < 2; i++ {
+		d.Do(f)
+		clock.Advance(30 * time.Second)
+	}
+	if callCount != 1 {
+		t.Errorf("expected 1 call, got %d", callCount)
+	}
+
+	// A different function restarts the timer as usual.
+	d.Do(f)
+	clock.Advance(30 * time.Second)
+	d.Do(func() {
+		callCount += 10
+	})
+	clock.Advance(30 * time.Second)
+	if callCount != 1 {
+		t.Errorf("expected 1 call, got %d", callCount)
+	}
+	clock.Advance(30 * time.Second)
+	if callCount != 11 {
+		t.Errorf("expected the last function to be called, got %d", callCount)
+	}
+}
+
+func TestWithNoOverlap(t *testing.T) {
+	d := debounce.NewDebouncer(time.Millisecond, 1000, debounce.WithNoOverlap())
+
+	var (
+		running  atomic.Int32
+		overlaps atomic.Int32
+		calls    atomic.Int32
+	)
+	f := func() {
+		if running.Add(1) > 1 {
+			overlaps.Add(1)
+		}
+		time.Sleep(20 * time.Millisecond)
+		running.Add(-1)
+		calls.Add(1)
+	}
+
+	// Keep firing while f is running.
+	for i := 0; i < 10; i++ {
+		d.Do(f)
+		time.Sleep(5 * time.Millisecond)
+	}
+	d.Flush()
+	d.Wait()
+
+	if n := overlaps.Load(); n != 0 {
+		t.Errorf("expected no overlapping executions, got %d", n)
+	}
+	// Colliding executions are deferred, not dropped, so the last call is
+	// always executed.
+	if n := calls.Load(); n < 2 {
+		t.Errorf("expected at least 2 calls, got %d", n)
+	}
+	if d.Pending() {
+		t.Error("expected no pending invocation")
+	}
+}
+
+func TestOnExecuteWithLatency(t *testing.T) {
+	clock := debounce.NewManualClock(time.Now())
+	d := debounce.NewDebouncer(time.Minute, 1000, debounce.WithTimerClock(clock))
+
+	var latencies []time.Duration
+	d.OnExecuteWithLatency(func(latency time.Duration) {
+		latencies = append(latencies, latency)
+	})
+
+	// The latency is measured from the first call of the burst.
+	for i := 0; i < 3; i++ {
+		d.Do(func() {})
+		clock.Advance(30 * time.Second)
+	}
+	clock.Advance(30 * time.Second)
+
+	if len(latencies) != 1 || latencies[0] != 2*time.Minute {
+		t.Errorf("expected a latency of 2m, got %v", latencies)
+	}
+	if latency := d.Stats().LastLatency; latency != 2*time.Minute {
+		t.Errorf("expected a last latency of 2m, got %s", latency)
+	}
+}
+
+func TestWithOnce(t *testing.T) {
+	var r debounce.Registry
+	clock := debounce.NewManualClock(time.Now())
+	d := debounce.NewDebouncer(time.Minute, 0, debounce.WithTimerClock(clock), debounce.WithOnce(), debounce.WithRegistry(&r))
+
+	callCount := 0
+	f := func() {
+		callCount++
+	}
+
+	// Canceling before the first execution doesn't count.
+	d.Do(f)
+	d.Cancel()
+	clock.Advance(time.Minute)
+
+	for i := 0; i < 3; i++ {
+		d.Do(f)
+	}
+	clock.Advance(time.Minute)
+	if callCount != 1 {
+		t.Errorf("expected 1 call, got %d", callCount)
+	}
+
+	// The debouncer is closed like with Close.
+	select {
+	case <-d.Done():
+	default:
+		t.Error("expected Done to be closed")
+	}
+	if n := r.Len(); n != 0 {
+		t.Errorf("expected the debouncer to leave its registry, got %d registered", n)
+	}
+
+	// Later calls are ignored.
+	d.Do(f)
+	clock.Advance(time.Minute)
+	if callCount != 1 || d.Pending() {
+		t.Errorf("expected no more calls, got %d", callCount)
+	}
+}
+
+func TestWithOnceLeading(t *testing.T) {
+	calls := 0
+	d := debounce.NewDebouncer(time.Hour, 0, debounce.WithOnce(), debounce.WithLeading(), debounce.WithReusableTimer())
+
+	d.Do(func() {
+		calls++
+	})
+	d.Do(func() {
+		calls++
+	})
+	if calls != 1 {
+		t.Errorf("expected 1 call, got %d", calls)
+	}
+
+	// Closed after the leading call, no burst is left to wait for.
+	if d.Pending() {
+		t.Error("expected no pending invocation")
+	}
+	done := make(chan struct{})
+	go func() {
+		d.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("expected Wait to return")
+	}
+}
+
+func TestWithReusableTimer(t *testing.T) {
+	var counter atomic.Int32
+	f := func() {
+		counter.Add(1)
+	}
+
+	d := debounce.NewDebouncer(30*time.Millisecond, 0, debounce.WithReusableTimer())
+	defer d.Close()
+
+	// The timer keeps being pushed out while the calls keep coming.
+	for i := 0; i < 10; i++ {
+		d.Do(f)
+		time.Sleep(5 * time.Millisecond)
+	}
+	if n := counter.Load(); n != 0 {
+		t.Errorf("expected 0 calls, got %d", n)
+	}
+	d.Wait()
+	if n := counter.Load(); n != 1 {
+		t.Errorf("expected 1 call, got %d", n)
+	}
+
+	// An earlier deadline wakes the timer up.
+	d.Do(f)
+	start := time.Now()
+	d.DoWithin(time.Millisecond, f)
+	d.Wait()
+	if elapsed := time.Since(start); elapsed > 25*time.Millisecond {
+		t.Errorf("expected the earlier deadline to apply, fired after %s", elapsed)
+	}
+
+	// Canceled invocations don't fire.
+	d.Do(f)
+	d.Cancel()
+	time.Sleep(50 * time.Millisecond)
+	if n := counter.Load(); n != 2 {
+		t.Errorf("expected 2 calls, got %d", n)
+	}
+}
+
+func TestWithReusableTimerRunsExecutionsConcurrently(t *testing.T) {
+	d := debounce.NewDebouncer(time.Millisecond, 0, debounce.WithReusableTimer())
+	defer d.Close()
+
+	// The first execution only returns once the second one has run, which
+	// deadlocks if the timer goroutine runs them one after the other.
+	second := make(chan struct{})
+	first := make(chan struct{})
+	d.Do(func() {
+		close(first)
+		<-second
+	})
+	<-first
+	d.Do(func() {
+		close(second)
+	})
+
+	select {
+	case <-second:
+	case <-time.After(time.Second):
+		t.Fatal("expected the second execution not to wait for the first")
+	}
+}
+
+func TestWithReusableTimerIgnoredByWrappers(t *testing.T) {
+	before := runtime.NumGoroutine()
+
+	for i := 0; i < 100; i++ {
+		debounce.New(time.Second, 0, debounce.WithReusableTimer())
+		debounce.NewWithArg[int](time.Second, 0, debounce.WithReusableTimer())
+		debounce.NewBatch(time.Second, 0, func([]int) {}, debounce.WithReusableTimer())
+		debounce.NewCountMap(time.Second, func(map[int]int) {}, debounce.WithReusableTimer())
+		debounce.NewResult[int](time.Second, debounce.WithReusableTimer())
+	}
+
+	// DebounceChannel closes its debouncer once the input is closed.
+	in := make(chan int)
+	out := debounce.DebounceChannel(in, time.Second, debounce.WithReusableTimer())
+	close(in)
+	for range out {
+	}
+
+	// Leave some leeway for goroutines of other tests winding down.
+	time.Sleep(10 * time.Millisecond)
+	if n := runtime.NumGoroutine() - before; n > 10 {
+		t.Errorf("expected no timer goroutines to be left running, got %d more goroutines", n)
+	}
+}
+
+func TestWithAdaptive(t *testing.T) {
+	clock := debounce.NewManualClock(time.Now())
+
+	var counter int
+	f := func() {
+		counter++
+	}
+
+	d := debounce.NewDebouncer(time.Second, 0, debounce.WithTimerClock(clock), debounce.WithAdaptive(100*time.Millisecond, time.Second))
+
+	// Until calls are observed, they are assumed to be sparse.
+	if interval := d.Interval(); interval != 100*time.Millisecond {
+		t.Errorf("expected an initial interval of 100ms, got %s", interval)
+	}
+
+	// Dense calls grow the interval toward the max.
+	for i := 0; i < 20; i++ {
+		d.Do(f)
+		clock.Advance(10 * time.Millisecond)
+	}
+	if interval := d.Interval(); interval != time.Second {
+		t.Errorf("expected dense calls to grow the interval to 1s, got %s", interval)
+	}
+	clock.Advance(900 * time.Millisecond)
+	if counter != 0 {
+		t.Errorf("expected 0 calls before the grown interval, got %d", counter)
+	}
+	clock.Advance(100 * time.Millisecond)
+	if counter != 1 {
+		t.Errorf("expected 1 call, got %d", counter)
+	}
+
+	// Sparse calls shrink it back toward the min.
+	for i := 0; i < 5; i++ {
+		clock.Advance(2 * time.Second)
+		d.Do(f)
+	}
+	if interval := d.Interval(); interval != 100*time.Millisecond {
+		t.Errorf("expected sparse calls to shrink the interval to 100ms, got %s", interval)
+	}
+	clock.Advance(100 * time.Millisecond)
+	if counter != 6 {
+		t.Errorf("expected 6 calls, got %d", counter)
+	}
+}
+
+func TestWithCooldown(t *testing.T) {
+	clock := debounce.NewManualClock(time.Now())
+
+	var counter int
+	f := func() {
+		counter++
+	}
+
+	d := debounce.NewDebouncer(time.Second, 0, debounce.WithTimerClock(clock), debounce.WithCooldown(5*time.Second))
+
+	d.Do(f)
+	clock.Advance(time.Second)
+	if counter != 1 {
+		t.Errorf("expected 1 call, got %d", counter)
+	}
+
+	// Calls during the cooldown are dropped.
+	for i := 0; i < 4; i++ {
+		d.Do(f)
+		clock.Advance(time.Second)
+	}
+	if counter != 1 || d.Pending() {
+		t.Errorf("expected the calls during the cooldown to be dropped, got %d calls", counter)
+	}
+	if dropped := d.Stats().Dropped; dropped != 4 {
+		t.Errorf("expected 4 dropped calls, got %d", dropped)
+	}
+
+	// TryDo reports the drop.
+	if d.TryDo(f) || d.Pending() {
+		t.Error("expected TryDo to drop f during the cooldown")
+	}
+	if dropped := d.Stats().Dropped; dropped != 5 {
+		t.Errorf("expected 5 dropped calls, got %d", dropped)
+	}
+
+	// Once it has elapsed, calls start a new burst.
+	clock.Advance(time.Second)
+	d.Do(f)
+	clock.Advance(time.Second)
+	if counter != 2 {
+		t.Errorf("expected 2 calls, got %d", counter)
+	}
+}
@@ -0,0 +1,78 @@
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package debounce
+
+import (
+	"math"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+)
+
+// NewPathDebouncer returns a PathDebouncer which collects the paths passed to
+// Touch, e.g. from filesystem change events, and passes them to handler once
+// they stop changing for the given duration. Paths are debounced per
+// directory, so a burst of changes in one directory doesn't hold back the
+// changes in another, and each path is passed on once per burst however often
+// it was touched.
+func NewPathDebouncer(after time.Duration, handler func(paths []string), opts ...Option) *PathDebouncer {
+	return &PathDebouncer{
+		k:       NewKeyedDebouncer[string](after, math.MaxUint64, opts...),
+		handler: handler,
+		dirs:    make(map[string]map[string]struct{}),
+	}
+}
+
+// A PathDebouncer debounces changed paths per directory.
+// See NewPathDebouncer.
+type PathDebouncer struct {
+	mu      sync.Mutex
+	k       *Keyed[string]
+	handler func(paths []string)
+	dirs    map[string]map[string]struct{}
+}
+
+// Touch records that path changed and schedules the changed paths of its
+// directory to be passed to the handler.
+func (p *PathDebouncer) Touch(path string) {
+	dir := filepath.Dir(path)
+
+	p.mu.Lock()
+	paths, ok := p.dirs[dir]
+	if !ok {
+		paths = make(map[string]struct{})
+		p.dirs[dir] = paths
+	}
+	paths[path] = struct{}{}
+	p.mu.Unlock()
+
+	p.k.Do(dir, func() {
+		p.flush(dir)
+	})
+}
+
+// Flush passes the changed paths of every directory to the handler right away.
+func (p *PathDebouncer) Flush() {
+	p.k.DrainAll()
+}
+
+// flush passes the changed paths of dir, sorted, to the handler.
+func (p *PathDebouncer) flush(dir string) {
+	p.mu.Lock()
+	set := p.dirs[dir]
+	delete(p.dirs, dir)
+	p.mu.Unlock()
+
+	if len(set) == 0 {
+		return
+	}
+
+	paths := make([]string, 0, len(set))
+	for path := range set {
+		paths = append(paths, path)
+	}
+	slices.Sort(paths)
+	p.handler(paths)
+}
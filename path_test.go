@@ -0,0 +1,45 @@
+package debounce_test
+
+import (
+	"reflect"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/qpoint-io/debounce"
+)
+
+func TestPathDebouncer(t *testing.T) {
+	clock := debounce.NewManualClock(time.Now())
+
+	var batches [][]string
+	handler := func(paths []string) {
+		batches = append(batches, paths)
+	}
+
+	p := debounce.NewPathDebouncer(time.Second, handler, debounce.WithTimerClock(clock))
+
+	// Paths are deduplicated within a burst.
+	for i := 0; i < 3; i++ {
+		p.Touch("src/b.go")
+		p.Touch("src/a.go")
+		clock.Advance(100 * time.Millisecond)
+	}
+	p.Touch("docs/README.md")
+
+	// src goes quiet first, docs is still changing.
+	clock.Advance(900 * time.Millisecond)
+	if want := [][]string{{"src/a.go", "src/b.go"}}; !reflect.DeepEqual(batches, want) {
+		t.Errorf("expected batches %v, got %v", want, batches)
+	}
+
+	p.Touch("src/a.go")
+	p.Flush()
+	slices.SortFunc(batches[1:], func(a, b []string) int {
+		return slices.Compare(a, b)
+	})
+	want := [][]string{{"src/a.go", "src/b.go"}, {"docs/README.md"}, {"src/a.go"}}
+	if !reflect.DeepEqual(batches, want) {
+		t.Errorf("expected batches %v, got %v", want, batches)
+	}
+}
@@ -0,0 +1,52 @@
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package debounce
+
+// A FireReason tells why a debouncer executed a function, e.g. to diagnose
+// unexpected immediate executions. See CountDebouncer.LastFireReason.
+type FireReason int
+
+const (
+	// FireNone means no function has been executed yet.
+	FireNone FireReason = iota
+
+	// FireTrailing means the debounced function stopped being called for
+	// the quiet period.
+	FireTrailing
+
+	// FireLeading means the function was executed on the first call of a
+	// burst, see WithLeading.
+	FireLeading
+
+	// FireCountLimit means the burst reached the count limit.
+	FireCountLimit
+
+	// FireMaxWait means the burst reached its max wait, or max duration,
+	// while the debounced function kept being called.
+	FireMaxWait
+
+	// FireFlush means the function was executed by Flush or one of its
+	// variants, such as FlushAfter or CloseFlush.
+	FireFlush
+)
+
+// String returns the name of the reason, e.g. "trailing".
+func (r FireReason) String() string {
+	switch r {
+	case FireNone:
+		return "none"
+	case FireTrailing:
+		return "trailing"
+	case FireLeading:
+		return "leading"
+	case FireCountLimit:
+		return "count limit"
+	case FireMaxWait:
+		return "max wait"
+	case FireFlush:
+		return "flush"
+	default:
+		return "unknown"
+	}
+}
@@ -0,0 +1,76 @@
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package debounce
+
+import "sync"
+
+// A Registry keeps track of debouncers created with WithRegistry, so that
+// they can all be flushed or closed at once, e.g. on shutdown. Debouncers are
+// removed from the registry when they are closed. The zero value is ready to
+// use.
+type Registry struct {
+	mu         sync.Mutex
+	debouncers map[registered]struct{}
+}
+
+// registered is implemented by the debouncers which can be registered.
+type registered interface {
+	Flush()
+	Close()
+}
+
+// FlushAll executes the pending function of every registered debouncer right
+// away.
+func (r *Registry) FlushAll() {
+	for _, d := range r.snapshot() {
+		d.Flush()
+	}
+}
+
+// CloseAll closes every registered debouncer, dropping their pending
+// invocations, and empties the registry. Combined with FlushAll, it executes
+// the pending functions first.
+func (r *Registry) CloseAll() {
+	for _, d := range r.snapshot() {
+		d.Close()
+	}
+}
+
+// Len returns the number of registered debouncers.
+func (r *Registry) Len() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return len(r.debouncers)
+}
+
+// snapshot returns the registered debouncers, so that they can be flushed or
+// closed without holding r.mu.
+func (r *Registry) snapshot() []registered {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	debouncers := make([]registered, 0, len(r.debouncers))
+	for d := range r.debouncers {
+		debouncers = append(debouncers, d)
+	}
+	return debouncers
+}
+
+func (r *Registry) add(d registered) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.debouncers == nil {
+		r.debouncers = make(map[registered]struct{})
+	}
+	r.debouncers[d] = struct{}{}
+}
+
+func (r *Registry) remove(d registered) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.debouncers, d)
+}
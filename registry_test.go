@@ -0,0 +1,53 @@
+package debounce_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/qpoint-io/debounce"
+)
+
+func TestRegistry(t *testing.T) {
+	var r debounce.Registry
+
+	callCount := 0
+	f := func() {
+		callCount++
+	}
+
+	d1 := debounce.NewDebouncer(time.Hour, 1000, debounce.WithRegistry(&r))
+	d2 := debounce.NewDurationDebouncer(time.Hour, 2*time.Hour, debounce.WithRegistry(&r))
+	d3 := debounce.NewDebouncer(time.Hour, 1000, debounce.WithRegistry(&r))
+
+	if n := r.Len(); n != 3 {
+		t.Errorf("expected 3 registered debouncers, got %d", n)
+	}
+
+	// Closed debouncers are deregistered.
+	d3.Close()
+	if n := r.Len(); n != 2 {
+		t.Errorf("expected 2 registered debouncers, got %d", n)
+	}
+
+	d1.Do(f)
+	d2.Do(f)
+	r.FlushAll()
+
+	if callCount != 2 {
+		t.Errorf("expected 2 calls, got %d", callCount)
+	}
+
+	d1.Do(f)
+	d2.Do(f)
+	r.CloseAll()
+
+	if n := r.Len(); n != 0 {
+		t.Errorf("expected no registered debouncers, got %d", n)
+	}
+	if d1.Pending() || d2.Pending() {
+		t.Error("expected no pending invocation")
+	}
+	if callCount != 2 {
+		t.Errorf("expected 2 calls, got %d", callCount)
+	}
+}
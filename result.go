@@ -0,0 +1,42 @@
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package debounce
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// NewResult is like New without a count limit, but f computes a result,
+// e.g. for a debounced recomputation whose consumers poll the latest value.
+// last returns the result of the most recently executed f and whether any
+// has been executed yet.
+func NewResult[R any](after time.Duration, opts ...Option) (trigger func(f func() R), last func() (R, bool)) {
+	var (
+		mu     sync.Mutex
+		result R
+		ok     bool
+	)
+
+	d := NewDebouncer(after, math.MaxUint64, unclosable(opts)...)
+
+	trigger = func(f func() R) {
+		d.Do(func() {
+			r := f()
+
+			mu.Lock()
+			defer mu.Unlock()
+			result, ok = r, true
+		})
+	}
+
+	last = func() (R, bool) {
+		mu.Lock()
+		defer mu.Unlock()
+		return result, ok
+	}
+
+	return trigger, last
+}
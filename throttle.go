@@ -0,0 +1,40 @@
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package debounce
+
+import (
+	"math"
+	"time"
+)
+
+// NewThrottle returns a throttled function that takes another function as its
+// argument. The function is executed immediately on the first call, after
+// which calls are ignored until the given interval has elapsed. Unlike
+// NewLeading, calls during the interval do not extend it, so a sustained
+// stream of calls executes f at most once per interval.
+func NewThrottle(interval time.Duration) func(f func()) {
+	d := NewDebouncer(interval, math.MaxUint64)
+	d.leading = true
+	d.throttle = true
+	return d.Do
+}
+
+// NewThrottleTrailing is like NewThrottle, but if the throttled function was
+// called during the interval, f is executed once more at its end with the last
+// function, which also starts the next interval. A sustained stream of calls
+// thus executes f once per interval, and the last call is never lost.
+func NewThrottleTrailing(interval time.Duration, opts ...Option) func(f func()) {
+	return NewThrottleTrailingDebouncer(interval, unclosable(opts)...).Do
+}
+
+// NewThrottleTrailingDebouncer returns a CountDebouncer with the same
+// semantics as NewThrottleTrailing, e.g. to be chained after another
+// debouncer with Chain.
+func NewThrottleTrailingDebouncer(interval time.Duration, opts ...Option) *CountDebouncer {
+	d := NewDebouncer(interval, math.MaxUint64, opts...)
+	d.leading = true
+	d.throttle = true
+	d.trailing = true
+	return d
+}
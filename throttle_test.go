@@ -0,0 +1,73 @@
+package debounce_test
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/qpoint-io/debounce"
+)
+
+func TestThrottle(t *testing.T) {
+	var counter uint64
+
+	f := func() {
+		atomic.AddUint64(&counter, 1)
+	}
+
+	throttled := debounce.NewThrottle(100 * time.Millisecond)
+
+	// The first call executes right away.
+	throttled(f)
+
+	if c := atomic.LoadUint64(&counter); c != 1 {
+		t.Fatal("Expected count 1, was", c)
+	}
+
+	// A sustained stream of calls executes once per interval.
+	deadline := time.Now().Add(450 * time.Millisecond)
+	for time.Now().Before(deadline) {
+		throttled(f)
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if c := atomic.LoadUint64(&counter); c < 4 || c > 6 {
+		t.Error("Expected count around 5, was", c)
+	}
+}
+
+func TestThrottleTrailing(t *testing.T) {
+	clock := debounce.NewManualClock(time.Now())
+	throttled := debounce.NewThrottleTrailing(100*time.Millisecond, debounce.WithTimerClock(clock))
+
+	var calls []int
+
+	// A burst of calls every 10ms spanning several intervals.
+	for i := 0; i <= 35; i++ {
+		throttled(func() {
+			calls = append(calls, i)
+		})
+		clock.Advance(10 * time.Millisecond)
+	}
+	clock.Advance(time.Second)
+
+	// The first call executes right away, then the last call of every
+	// interval at its end, including the final one.
+	want := []int{0, 9, 19, 29, 35}
+	if len(calls) != len(want) {
+		t.Fatalf("expected calls %v, got %v", want, calls)
+	}
+	for i := range want {
+		if calls[i] != want[i] {
+			t.Fatalf("expected calls %v, got %v", want, calls)
+		}
+	}
+
+	// Once quiet, the next call executes right away again.
+	throttled(func() {
+		calls = append(calls, 100)
+	})
+	if calls[len(calls)-1] != 100 {
+		t.Error("expected the call to execute right away")
+	}
+}